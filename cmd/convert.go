@@ -16,9 +16,11 @@ package cmd
 
 import (
 	"context"
-	"encoding/json"
+	"io"
+	"io/ioutil"
 	"os"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -28,7 +30,8 @@ var convertCmd = &cobra.Command{
 	Use:   "convert <tfplan>",
 	Short: "Convert resources in a Terraform plan to their Google CAI representation.",
 	Long: `Convert (terraform-validator convert) will convert a Terraform plan file
-into CAI (Cloud Asset Inventory) resources and output them as a JSON array.
+into CAI (Cloud Asset Inventory) resources and output them, by default as a
+JSON array on stdout.
 
 Note:
   Only supported resources will be converted. Non supported resources are
@@ -36,6 +39,59 @@ Note:
   Run "terraform-validator list-supported-resources" to see all supported
   resources.
 
+  With --include-metadata, each asset has an additional "terraform_metadata"
+  key listing the Terraform resource changes that contributed to it.
+
+  --include and --exclude select the resources to convert by address glob
+  (e.g. "module.net.*"), resource type or provider name. Filtered out
+  resources are listed on stderr.
+
+  A summary of the resource changes that were (not) converted is printed on
+  stderr, after the list of the resources the plan deletes or replaces. With
+  --strict, the command fails if any google resource could not be converted.
+
+  With --include-deletions, the resources deleted or replaced by the plan are output as
+  tombstones: assets converted from the values before the deletion, marked
+  with "deleted": true. The protobuf formats have no deletion marker and do
+  not support --include-deletions.
+
+  With --continue-on-error, resources that fail to convert are listed on
+  stderr instead of aborting the conversion, and the exit code is 3.
+
+  Attributes unknown to the built-in provider schema are not converted and
+  are listed on stderr. With --provider-schema (the output of
+  "terraform providers schema -json"), they are read with the schema of the
+  provider the plan was created with.
+
+  With --mappings-dir, resource types that are not supported yet are
+  converted with the declarative YAML mappings in the given directory.
+  With --plugins-dir, they are converted by the converter plugins
+  (terraform-validator-converter-* executables) in the given directory.
+
+  With --transforms, the converted assets are edited by the set, rename and
+  delete transforms of the given YAML file.
+
+  Assets and their org policies all get the time of the run as their update time.
+  --timestamp sets a fixed time (RFC 3339, e.g. "2021-04-14T15:16:17Z") for
+  reproducible output, and --timestamp=plan uses the time the plan was
+  created at (Terraform 1.5+ plans).
+
+  --format selects the output format:
+    json        a JSON array of assets (default)
+    ndjson      one JSON asset per line, like "gcloud asset export"
+    yaml        one YAML document per asset
+    proto-json  one validator.Asset protobuf message per line, in JSON
+    proto       binary validator.Asset protobuf messages, each prefixed
+                with its size as a varint
+  The protobuf formats do not have the IAM audit configs, v2 org policies,
+  ancestors and update time of assets, nor --include-metadata. --output writes the
+  assets to a file instead of stdout.
+  The ndjson and protobuf formats are streamed: assets that no later resource
+  change can merge into (e.g. buckets, but not projects with IAM members) are
+  written as soon as they are converted, and the others once the whole plan
+  is converted. With --strict, streamed assets may be written before the
+  command fails.
+
 Example:
   terraform-validator convert ./example/terraform.tfplan --project my-project \
     --ancestry organization/my-org/folder/my-folder
@@ -47,22 +103,106 @@ Example:
 		if flags.convert.offline && flags.convert.ancestry == "" {
 			return errors.New("please set ancestry via --ancestry in offline mode")
 		}
+		if _, err := newAssetWriter(ioutil.Discard, flags.convert.format, flags.convert.includeMetadata); err != nil {
+			return err
+		}
+		if flags.convert.deletions && isProtoFormat(flags.convert.format) {
+			return errors.Errorf("--include-deletions is not supported with --format=%s", flags.convert.format)
+		}
 		return nil
 	},
 	RunE: func(c *cobra.Command, args []string) error {
 		ctx := context.Background()
-		assets, err := tfgcv.ReadPlannedAssets(ctx, args[0], flags.convert.project, flags.convert.ancestry, flags.convert.offline)
+		report := &tfgcv.Report{}
+		opts, err := readOptions(flags.convert.readFlags, report)
+		if err != nil {
+			return err
+		}
+		w, closeOutput, err := createOutput(flags.convert.output)
+		if err != nil {
+			return err
+		}
+		defer closeOutput()
+		aw, err := newAssetWriter(w, flags.convert.format, flags.convert.includeMetadata)
 		if err != nil {
+			return err
+		}
+		if isStreamFormat(flags.convert.format) {
+			opts = append(opts, tfgcv.WithStream(func(asset google.Asset) error {
+				return errors.Wrap(aw.Write(asset), "writing output")
+			}))
+		}
+		assets, err := tfgcv.ReadPlannedAssets(ctx, args[0], flags.convert.project, flags.convert.ancestry, flags.convert.offline, opts...)
+		if err != nil && !isIncomplete(err) {
 			if errors.Cause(err) == tfgcv.ErrParsingProviderProject {
 				return errors.New("unable to parse provider project, please use --project flag")
 			}
 			return errors.Wrap(err, "converting tfplan to CAI assets")
 		}
+		printReport(os.Stderr, report)
+		if flags.convert.strict {
+			if err := checkStrict(report); err != nil {
+				return err
+			}
+		}
 
-		if err := json.NewEncoder(os.Stdout).Encode(assets); err != nil {
-			return errors.Wrap(err, "encoding json")
+		for _, asset := range assets {
+			if err := aw.Write(asset); err != nil {
+				return errors.Wrap(err, "writing output")
+			}
+		}
+		if err := aw.Close(); err != nil {
+			return errors.Wrap(err, "writing output")
+		}
+		if err := closeOutput(); err != nil {
+			return err
 		}
 
+		if len(report.Errors) > 0 {
+			os.Exit(exitIncomplete)
+		}
 		return nil
 	},
 }
+
+// assetWithMetadata is the output format of an asset with --include-metadata.
+type assetWithMetadata struct {
+	google.Asset
+	Metadata assetMetadata `json:"terraform_metadata"`
+}
+
+type assetMetadata struct {
+	SourcePlans []string            `json:"source_plans,omitempty"`
+	Provenance  []google.Provenance `json:"provenance"`
+}
+
+func withMetadata(a google.Asset) assetWithMetadata {
+	return assetWithMetadata{
+		Asset: a,
+		Metadata: assetMetadata{
+			SourcePlans: a.SourcePlans,
+			Provenance:  a.Provenance,
+		},
+	}
+}
+
+// createOutput creates the output file, or returns stdout if output is
+// empty. The returned function closes the file; it may be called again, e.g.
+// deferred.
+func createOutput(output string) (io.Writer, func() error, error) {
+	if output == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "creating output file")
+	}
+	closed := false
+	return f, func() error {
+		if closed {
+			return nil
+		}
+		closed = true
+		return errors.Wrap(f.Close(), "closing output file")
+	}, nil
+}
@@ -0,0 +1,213 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Output formats of converted assets.
+const (
+	// formatJSON is a JSON array of assets.
+	formatJSON = "json"
+	// formatNDJSON is one JSON asset per line, like "gcloud asset export".
+	formatNDJSON = "ndjson"
+	// formatYAML is one YAML document per asset.
+	formatYAML = "yaml"
+	// formatProtoJSON is one validator.Asset message per line, in the JSON
+	// mapping of protobuf.
+	formatProtoJSON = "proto-json"
+	// formatProto is a stream of binary validator.Asset messages, each
+	// prefixed with its size as a varint.
+	formatProto = "proto"
+)
+
+var outputFormats = []string{formatJSON, formatNDJSON, formatYAML, formatProtoJSON, formatProto}
+
+// assetWriter encodes converted assets in one of the output formats. The
+// streaming formats (see isStreamFormat) write the assets that no later
+// resource change can merge into while the plan is converted, and the others
+// once the whole plan is converted.
+type assetWriter interface {
+	// Write writes one asset.
+	Write(asset google.Asset) error
+	// Close terminates the output. It does not close the underlying writer.
+	Close() error
+}
+
+// newAssetWriter returns a writer of assets to w in the given format. With
+// includeMetadata, assets have their Terraform metadata, which the protobuf
+// formats have no room for.
+func newAssetWriter(w io.Writer, format string, includeMetadata bool) (assetWriter, error) {
+	if includeMetadata && isProtoFormat(format) {
+		return nil, fmt.Errorf("--include-metadata is not supported with --format=%s", format)
+	}
+	switch format {
+	case formatJSON:
+		return &jsonArrayWriter{w: w, includeMetadata: includeMetadata}, nil
+	case formatNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w), includeMetadata: includeMetadata}, nil
+	case formatYAML:
+		return &yamlWriter{enc: yaml.NewEncoder(w), includeMetadata: includeMetadata}, nil
+	case formatProtoJSON:
+		return &protoJSONWriter{w: w}, nil
+	case formatProto:
+		return &protoWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, must be one of %s", format, strings.Join(outputFormats, ", "))
+}
+
+// isStreamFormat reports whether the format has one asset per line or
+// message, so that assets can be written while the plan is converted.
+func isStreamFormat(format string) bool {
+	return format == formatNDJSON || isProtoFormat(format)
+}
+
+// isProtoFormat reports whether the format encodes validator.Asset messages.
+func isProtoFormat(format string) bool {
+	return format == formatProtoJSON || format == formatProto
+}
+
+// outputAsset returns the value to encode for an asset in the JSON based
+// formats.
+func outputAsset(asset google.Asset, includeMetadata bool) interface{} {
+	if includeMetadata {
+		return withMetadata(asset)
+	}
+	return asset
+}
+
+type jsonArrayWriter struct {
+	w               io.Writer
+	includeMetadata bool
+	count           int
+}
+
+func (a *jsonArrayWriter) Write(asset google.Asset) error {
+	b, err := json.Marshal(outputAsset(asset, a.includeMetadata))
+	if err != nil {
+		return errors.Wrapf(err, "encoding asset %s", asset.Name)
+	}
+	sep := ","
+	if a.count == 0 {
+		sep = "["
+	}
+	a.count++
+	if _, err := io.WriteString(a.w, sep); err != nil {
+		return err
+	}
+	_, err = a.w.Write(b)
+	return err
+}
+
+func (a *jsonArrayWriter) Close() error {
+	end := "]\n"
+	if a.count == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(a.w, end)
+	return err
+}
+
+type ndjsonWriter struct {
+	enc             *json.Encoder
+	includeMetadata bool
+}
+
+func (n *ndjsonWriter) Write(asset google.Asset) error {
+	return errors.Wrapf(n.enc.Encode(outputAsset(asset, n.includeMetadata)), "encoding asset %s", asset.Name)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return nil
+}
+
+type yamlWriter struct {
+	enc             *yaml.Encoder
+	includeMetadata bool
+}
+
+func (y *yamlWriter) Write(asset google.Asset) error {
+	// Assets only have JSON tags: they are converted to YAML through JSON,
+	// into a yaml.MapSlice to keep the order of their fields.
+	b, err := json.Marshal(outputAsset(asset, y.includeMetadata))
+	if err != nil {
+		return errors.Wrapf(err, "encoding asset %s", asset.Name)
+	}
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return errors.Wrapf(err, "encoding asset %s", asset.Name)
+	}
+	return errors.Wrapf(y.enc.Encode(doc), "encoding asset %s", asset.Name)
+}
+
+func (y *yamlWriter) Close() error {
+	return y.enc.Close()
+}
+
+type protoJSONWriter struct {
+	w io.Writer
+}
+
+func (p *protoJSONWriter) Write(asset google.Asset) error {
+	pb, err := tfgcv.AssetToProto(asset)
+	if err != nil {
+		return err
+	}
+	s, err := (&jsonpb.Marshaler{}).MarshalToString(pb)
+	if err != nil {
+		return errors.Wrapf(err, "encoding asset %s", asset.Name)
+	}
+	_, err = io.WriteString(p.w, s+"\n")
+	return err
+}
+
+func (p *protoJSONWriter) Close() error {
+	return nil
+}
+
+type protoWriter struct {
+	w io.Writer
+}
+
+func (p *protoWriter) Write(asset google.Asset) error {
+	pb, err := tfgcv.AssetToProto(asset)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(pb)
+	if err != nil {
+		return errors.Wrapf(err, "encoding asset %s", asset.Name)
+	}
+	if _, err := p.w.Write(proto.EncodeVarint(uint64(len(b)))); err != nil {
+		return err
+	}
+	_, err = p.w.Write(b)
+	return err
+}
+
+func (p *protoWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/forseti-security/config-validator/pkg/api/validator"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func testAssets() []google.Asset {
+	return []google.Asset{
+		{
+			Name:       "//storage.googleapis.com/bucket-a",
+			Type:       "storage.googleapis.com/Bucket",
+			Ancestry:   "organization/123/project/foo",
+			Ancestors:  []string{"projects/foo", "organizations/123"},
+			UpdateTime: &google.Timestamp{Seconds: 1618413377},
+			Resource: &google.AssetResource{
+				Version:  "v1",
+				Location: "us",
+				Data:     map[string]interface{}{"name": "bucket-a"},
+			},
+		},
+		{
+			Name:     "//storage.googleapis.com/bucket-b",
+			Type:     "storage.googleapis.com/Bucket",
+			Ancestry: "organization/123/project/foo",
+		},
+	}
+}
+
+func writeTestAssets(t *testing.T, assets []google.Asset, format string, includeMetadata bool) string {
+	var buf bytes.Buffer
+	w, err := newAssetWriter(&buf, format, includeMetadata)
+	require.NoError(t, err)
+	for _, a := range assets {
+		require.NoError(t, w.Write(a))
+	}
+	require.NoError(t, w.Close())
+	return buf.String()
+}
+
+func TestAssetWriter_json(t *testing.T) {
+	assets := testAssets()
+	want, err := json.Marshal(assets)
+	require.NoError(t, err)
+	assert.Equal(t, string(want)+"\n", writeTestAssets(t, assets, formatJSON, false))
+	assert.Equal(t, "[]\n", writeTestAssets(t, nil, formatJSON, false))
+
+	var got []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(writeTestAssets(t, assets, formatJSON, true)), &got))
+	require.Len(t, got, 2)
+	assert.Contains(t, got[0], "terraform_metadata")
+}
+
+func TestAssetWriter_ndjson(t *testing.T) {
+	assets := testAssets()
+	lines := strings.Split(strings.TrimSuffix(writeTestAssets(t, assets, formatNDJSON, false), "\n"), "\n")
+	require.Len(t, lines, 2)
+	for i, line := range lines {
+		var got google.Asset
+		require.NoError(t, json.Unmarshal([]byte(line), &got))
+		assert.Equal(t, assets[i].Name, got.Name)
+	}
+}
+
+func TestAssetWriter_yaml(t *testing.T) {
+	out := writeTestAssets(t, testAssets(), formatYAML, false)
+	assert.True(t, strings.HasPrefix(out, "name: //storage.googleapis.com/bucket-a\nasset_type: storage.googleapis.com/Bucket\n"), out)
+
+	dec := yaml.NewDecoder(strings.NewReader(out))
+	var docs []map[string]interface{}
+	for {
+		var doc map[string]interface{}
+		if err := dec.Decode(&doc); err != nil {
+			break
+		}
+		docs = append(docs, doc)
+	}
+	require.Len(t, docs, 2)
+	assert.Equal(t, "us", docs[0]["resource"].(map[interface{}]interface{})["location"])
+	assert.Equal(t, "//storage.googleapis.com/bucket-b", docs[1]["name"])
+}
+
+func TestAssetWriter_protoJSON(t *testing.T) {
+	lines := strings.Split(strings.TrimSuffix(writeTestAssets(t, testAssets(), formatProtoJSON, false), "\n"), "\n")
+	require.Len(t, lines, 2)
+	got := &validator.Asset{}
+	require.NoError(t, jsonpb.UnmarshalString(lines[0], got))
+	assert.Equal(t, "//storage.googleapis.com/bucket-a", got.Name)
+	assert.Equal(t, "us", got.Resource.Location)
+	assert.Equal(t, "organization/123/project/foo", got.AncestryPath)
+}
+
+func TestAssetWriter_proto(t *testing.T) {
+	out := []byte(writeTestAssets(t, testAssets(), formatProto, false))
+	var got []*validator.Asset
+	for len(out) > 0 {
+		size, n := proto.DecodeVarint(out)
+		require.NotZero(t, n)
+		msg := &validator.Asset{}
+		require.NoError(t, proto.Unmarshal(out[n:n+int(size)], msg))
+		got = append(got, msg)
+		out = out[n+int(size):]
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "//storage.googleapis.com/bucket-a", got[0].Name)
+	assert.Equal(t, "storage.googleapis.com/Bucket", got[1].AssetType)
+}
+
+func TestNewAssetWriter_invalid(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newAssetWriter(&buf, "xml", false)
+	assert.Error(t, err)
+	_, err = newAssetWriter(&buf, formatProto, true)
+	assert.Error(t, err)
+}
@@ -17,7 +17,7 @@ package cmd
 import (
 	"fmt"
 
-	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/spf13/cobra"
 )
 
@@ -25,7 +25,23 @@ var listSupportedResourcesCmd = &cobra.Command{
 	Use:   "list-supported-resources",
 	Short: "List supported terraform resources.",
 	RunE: func(c *cobra.Command, args []string) error {
-		list := converter.SupportedTerraformResources()
+		var mappings []*google.Mapping
+		if flags.listSupportedResources.mappingsDir != "" {
+			var err error
+			mappings, err = google.LoadMappings(flags.listSupportedResources.mappingsDir)
+			if err != nil {
+				return err
+			}
+		}
+		var plugins []*google.Plugin
+		if flags.listSupportedResources.pluginsDir != "" {
+			var err error
+			plugins, err = google.LoadPlugins(flags.listSupportedResources.pluginsDir, flags.listSupportedResources.pluginTimeout)
+			if err != nil {
+				return err
+			}
+		}
+		list := google.SupportedTerraformResources(mappings, plugins)
 
 		for _, resource := range list {
 			fmt.Println(resource)
@@ -0,0 +1,179 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/pkg/errors"
+)
+
+// exitIncomplete is the exit code of a run in which some resources could not
+// be converted with --continue-on-error.
+const exitIncomplete = 3
+
+// readFlags are the flags of convert and validate that control how plans are
+// read.
+type readFlags struct {
+	include         []string
+	exclude         []string
+	strict          bool
+	continueOnError bool
+	providerSchema  string
+	mappingsDir     string
+	pluginsDir      string
+	pluginTimeout   time.Duration
+	transforms      string
+	timestamp       string
+	deletions       bool
+}
+
+// planTimestamp is the value of --timestamp that uses the creation time of
+// each plan.
+const planTimestamp = "plan"
+
+// readOptions returns the tfgcv options for the given flags, recording into
+// report.
+func readOptions(f readFlags, report *tfgcv.Report) ([]tfgcv.Option, error) {
+	opts := []tfgcv.Option{
+		tfgcv.WithInclude(f.include...),
+		tfgcv.WithExclude(f.exclude...),
+		tfgcv.WithReport(report),
+	}
+	if f.continueOnError {
+		opts = append(opts, tfgcv.WithContinueOnError())
+	}
+	if f.providerSchema != "" {
+		opts = append(opts, tfgcv.WithProviderSchemaFile(f.providerSchema))
+	}
+	if f.mappingsDir != "" {
+		opts = append(opts, tfgcv.WithMappingsDir(f.mappingsDir))
+	}
+	if f.pluginsDir != "" {
+		opts = append(opts, tfgcv.WithPluginsDir(f.pluginsDir), tfgcv.WithPluginTimeout(f.pluginTimeout))
+	}
+	if f.transforms != "" {
+		opts = append(opts, tfgcv.WithTransformsFile(f.transforms))
+	}
+	if f.deletions {
+		opts = append(opts, tfgcv.WithDeletions())
+	}
+	switch f.timestamp {
+	case "":
+	case planTimestamp:
+		opts = append(opts, tfgcv.WithPlanTimestamp())
+	default:
+		t, err := time.Parse(time.RFC3339, f.timestamp)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing --timestamp %q", f.timestamp)
+		}
+		opts = append(opts, tfgcv.WithTimestamp(t))
+	}
+	return opts, nil
+}
+
+// isIncomplete reports whether err only signals that some resources could not
+// be converted with --continue-on-error.
+func isIncomplete(err error) bool {
+	var incomplete *tfgcv.IncompleteError
+	return errors.As(err, &incomplete)
+}
+
+// printReport prints the report of reading the plans in text format.
+func printReport(w io.Writer, report *tfgcv.Report) {
+	if deletions := report.Deletions(); len(deletions) > 0 {
+		fmt.Fprintf(w, "Planned deletion of %d resource(s):\n", len(deletions))
+		for _, d := range deletions {
+			fmt.Fprintf(w, "  %v [%v]\n", d.Address, d.Action)
+		}
+		fmt.Fprintln(w)
+	}
+	printConversionReport(w, report)
+}
+
+// printDriftReport prints the report of reading the resource drift of the
+// plans in text format. Drifted objects deleted outside of Terraform are not
+// planned deletions, so they are only listed in the coverage summary.
+func printDriftReport(w io.Writer, report *tfgcv.Report) {
+	if len(report.Filtered) == 0 && len(report.Coverage) == 0 && len(report.SchemaDrift) == 0 && len(report.Errors) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Resource drift:")
+	printConversionReport(w, report)
+}
+
+// printConversionReport prints the filtered resources, coverage summary,
+// schema drift and conversion errors of a report.
+func printConversionReport(w io.Writer, report *tfgcv.Report) {
+	if len(report.Filtered) > 0 {
+		fmt.Fprintf(w, "Filtered out %d resource(s):\n", len(report.Filtered))
+		for _, f := range report.Filtered {
+			fmt.Fprintf(w, "  %v: %v\n", f.Address, f.Reason)
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Coverage) > 0 {
+		counts := report.CoverageCounts()
+		fmt.Fprintf(w, "Converted %d of %d resource change(s): %d unsupported, %d non-Google, %d skipped, %d error(s)\n",
+			counts[google.CoverageConverted],
+			len(report.Coverage),
+			counts[google.CoverageUnsupported],
+			counts[google.CoverageNonGoogle],
+			counts[google.CoverageSkipped],
+			counts[google.CoverageError],
+		)
+		for _, c := range report.Coverage {
+			switch c.Status {
+			case google.CoverageUnsupported, google.CoverageNonGoogle, google.CoverageError:
+				fmt.Fprintf(w, "  %v: %v (%v)\n", c.Address, c.Status, c.Reason)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.SchemaDrift) > 0 {
+		fmt.Fprintf(w, "Found %d attribute(s) unknown to the conversion schema, which are not converted:\n", len(report.SchemaDrift))
+		for _, d := range report.SchemaDrift {
+			known := ""
+			if d.KnownToProvider {
+				known = " (known to the provider schema)"
+			}
+			fmt.Fprintf(w, "  %v.%v%v: %v\n", d.Type, d.Attribute, known, strings.Join(d.Addresses, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+
+	if len(report.Errors) > 0 {
+		fmt.Fprintf(w, "Failed to convert %d resource(s):\n", len(report.Errors))
+		for _, e := range report.Errors {
+			fmt.Fprintf(w, "  %v\n", e)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// checkStrict returns an error if any google resource could not be converted.
+func checkStrict(report *tfgcv.Report) error {
+	if uncovered := report.Uncovered(); len(uncovered) > 0 {
+		return errors.Errorf("--strict: %d google resource(s) could not be converted", len(uncovered))
+	}
+	return nil
+}
@@ -19,7 +19,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"time"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/spf13/cobra"
 )
 
@@ -35,10 +37,41 @@ func init() {
 	validateCmd.Flags().StringVar(&flags.validate.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
 	validateCmd.Flags().BoolVar(&flags.validate.offline, "offline", false, "Do not make network requests")
 	validateCmd.Flags().BoolVar(&flags.validate.outputJSON, "output-json", false, "Print violations as JSON")
+	validateCmd.Flags().BoolVar(&flags.validate.merge, "merge", false, "Merge the resources of several plans into one inventory before validating")
+	validateCmd.Flags().BoolVar(&flags.validate.includeDrift, "include-drift", false, "Also validate objects changed outside of Terraform (resource drift)")
+	validateCmd.Flags().StringSliceVar(&flags.validate.include, "include", nil, "Only validate resources whose address, type or provider matches one of these glob patterns")
+	validateCmd.Flags().BoolVar(&flags.validate.continueOnError, "continue-on-error", false, "Keep validating the other resources when a resource fails to convert, and exit with code 3")
+	validateCmd.Flags().BoolVar(&flags.validate.strict, "strict", false, "Fail if any google resource in the plan could not be converted")
+	validateCmd.Flags().StringSliceVar(&flags.validate.exclude, "exclude", nil, "Do not validate resources whose address, type or provider matches one of these glob patterns")
+	validateCmd.Flags().StringVar(&flags.validate.providerSchema, "provider-schema", "", "Path to the output of \"terraform providers schema -json\", used to read attributes unknown to the built-in provider schema")
+	validateCmd.Flags().StringVar(&flags.validate.mappingsDir, "mappings-dir", "", "Directory of YAML files mapping additional resource types to CAI assets")
+	validateCmd.Flags().StringVar(&flags.validate.pluginsDir, "plugins-dir", "", "Directory of converter plugins (executables named terraform-validator-converter-*)")
+	validateCmd.Flags().DurationVar(&flags.validate.pluginTimeout, "plugin-timeout", google.DefaultPluginTimeout, "Time a converter plugin may run for on one resource before it is killed")
+	validateCmd.Flags().StringVar(&flags.validate.transforms, "transforms", "", "YAML file of transforms that set, rename or delete fields of the converted assets")
+	validateCmd.Flags().StringVar(&flags.validate.timestamp, "timestamp", "", "Update time of converted assets and org policies: an RFC 3339 time, or \"plan\" for the time the plan was created at (default: the current time)")
+	validateCmd.Flags().BoolVar(&flags.validate.deletions, "include-deletions", false, "Validate the resources deleted or replaced by the plan as assets marked \"deleted\"")
 
 	convertCmd.Flags().StringVar(&flags.convert.project, "project", "", "Provider project override (override the default project configuration assigned to the google terraform provider when converting resources)")
 	convertCmd.Flags().StringVar(&flags.convert.ancestry, "ancestry", "", "Override the ancestry location of the project when validating resources")
 	convertCmd.Flags().BoolVar(&flags.convert.offline, "offline", false, "Do not make network requests")
+	convertCmd.Flags().BoolVar(&flags.convert.includeMetadata, "include-metadata", false, "Add the Terraform resources that contributed to each asset under \"terraform_metadata\"")
+	convertCmd.Flags().StringSliceVar(&flags.convert.include, "include", nil, "Only convert resources whose address, type or provider matches one of these glob patterns")
+	convertCmd.Flags().BoolVar(&flags.convert.continueOnError, "continue-on-error", false, "Keep converting the other resources when a resource fails to convert, and exit with code 3")
+	convertCmd.Flags().BoolVar(&flags.convert.strict, "strict", false, "Fail if any google resource in the plan could not be converted")
+	convertCmd.Flags().StringSliceVar(&flags.convert.exclude, "exclude", nil, "Do not convert resources whose address, type or provider matches one of these glob patterns")
+	convertCmd.Flags().StringVar(&flags.convert.providerSchema, "provider-schema", "", "Path to the output of \"terraform providers schema -json\", used to read attributes unknown to the built-in provider schema")
+	convertCmd.Flags().StringVar(&flags.convert.mappingsDir, "mappings-dir", "", "Directory of YAML files mapping additional resource types to CAI assets")
+	convertCmd.Flags().StringVar(&flags.convert.pluginsDir, "plugins-dir", "", "Directory of converter plugins (executables named terraform-validator-converter-*)")
+	convertCmd.Flags().DurationVar(&flags.convert.pluginTimeout, "plugin-timeout", google.DefaultPluginTimeout, "Time a converter plugin may run for on one resource before it is killed")
+	convertCmd.Flags().StringVar(&flags.convert.transforms, "transforms", "", "YAML file of transforms that set, rename or delete fields of the converted assets")
+	convertCmd.Flags().StringVar(&flags.convert.timestamp, "timestamp", "", "Update time of converted assets and org policies: an RFC 3339 time, or \"plan\" for the time the plan was created at (default: the current time)")
+	convertCmd.Flags().BoolVar(&flags.convert.deletions, "include-deletions", false, "Output the resources deleted or replaced by the plan as assets marked \"deleted\"")
+	convertCmd.Flags().StringVar(&flags.convert.format, "format", formatJSON, "Output format: json, ndjson, yaml, proto-json or proto (ndjson and the proto formats are streamed)")
+	convertCmd.Flags().StringVar(&flags.convert.output, "output", "", "Write the converted assets to this file instead of stdout")
+
+	listSupportedResourcesCmd.Flags().StringVar(&flags.listSupportedResources.mappingsDir, "mappings-dir", "", "Directory of YAML files mapping additional resource types to CAI assets")
+	listSupportedResourcesCmd.Flags().StringVar(&flags.listSupportedResources.pluginsDir, "plugins-dir", "", "Directory of converter plugins (executables named terraform-validator-converter-*)")
+	listSupportedResourcesCmd.Flags().DurationVar(&flags.listSupportedResources.pluginTimeout, "plugin-timeout", google.DefaultPluginTimeout, "Time a converter plugin may run for before it is killed")
 
 	rootCmd.AddCommand(convertCmd)
 	rootCmd.AddCommand(listSupportedResourcesCmd)
@@ -55,18 +88,29 @@ var flags struct {
 
 	// flags that correspond to subcommands:
 	convert struct {
-		project  string
-		ancestry string
-		offline    bool
+		project         string
+		ancestry        string
+		offline         bool
+		includeMetadata bool
+		format          string
+		output          string
+		readFlags
 	}
 	validate struct {
-		project    string
-		ancestry   string
-		offline    bool
-		policyPath string
-		outputJSON bool
+		project      string
+		ancestry     string
+		offline      bool
+		policyPath   string
+		outputJSON   bool
+		includeDrift bool
+		merge        bool
+		readFlags
+	}
+	listSupportedResources struct {
+		mappingsDir   string
+		pluginsDir    string
+		pluginTimeout time.Duration
 	}
-	listSupportedResources struct{}
 }
 
 // Execute is the entry-point for all commands.
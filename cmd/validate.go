@@ -18,15 +18,18 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/GoogleCloudPlatform/terraform-validator/tfgcv"
+	"github.com/forseti-security/config-validator/pkg/api/validator"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
 var validateCmd = &cobra.Command{
-	Use:   "validate <tfplan>",
+	Use:   "validate <tfplan> [<tfplan>...]",
 	Short: "Validate resources in a Terraform plan by calling Forseti Config Validator.",
 	Long: `Validate (terraform-validator validate) converts supported Terraform
 resources (see: "terraform-validate list-supported-resources") into their CAI
@@ -34,16 +37,76 @@ resources (see: "terraform-validate list-supported-resources") into their CAI
 returning the violations. If any violations are reported an exit code of 2
 is set.
 
+With --include-drift, objects that Terraform detected as changed outside of
+Terraform (the "resource_drift" section of Terraform 0.15.4+ plans) are
+validated as well and their violations are reported separately.
+
+With --merge, several plans (e.g. of separate Terraform roots deploying into
+the same projects) are converted into a single inventory and validated
+together. Each violation is attributed to the plans that contributed to the
+violating asset.
+
+Resources moved by "moved" blocks are validated at their new address. The
+violations of a moved and updated resource that it already had before the
+move are reported separately, as not introduced by the plan; they still set
+the exit code of 2.
+
+With --include and --exclude, only the resources whose address glob
+(e.g. "module.net.*"), resource type or provider name match are validated.
+Filtered out resources are listed on stderr.
+
+A summary of the resource changes that were (not) converted, and therefore
+(not) validated, is printed on stderr, after the list of the resources the
+plan deletes or replaces. With --strict, the command fails if any google
+resource could not be converted. With --include-drift, the same summary,
+--strict and --continue-on-error apply to the resource drift.
+
+Deleted resources are not validated by default. With --include-deletions,
+they are validated as tombstones: assets converted from the values before
+the deletion, marked with "deleted": true, so that constraints can forbid
+deleting e.g. KMS keys or production buckets. Replaced resources get a
+tombstone next to the asset of their replacement. Only the constraints
+annotated with terraform-validator/deletions: "true" review tombstones.
+
+With --continue-on-error, resources that fail to convert are listed on stderr
+instead of aborting the validation, and all other resources are validated.
+The exit code is then 3, even if violations were found, to signal that the
+validation was incomplete.
+
+Attributes in the plan that are unknown to the provider schema built into
+terraform-validator (e.g. added by a newer provider version) are not
+converted and are listed on stderr. With --provider-schema, they are read
+with the schema of the provider the plan was created with.
+
+With --mappings-dir, resource types that are not supported yet are converted
+with the declarative YAML mappings in the given directory. With --plugins-dir,
+they are converted by the converter plugins (terraform-validator-converter-*
+executables) in the given directory.
+
+With --transforms, the converted assets are edited before validation by the
+set, rename and delete transforms of the given YAML file.
+
+Assets and their org policies all get the time of the run as their update time,
+which time-based constraints may check. --timestamp sets a fixed time (RFC 3339, e.g.
+"2021-04-14T15:16:17Z") for reproducible runs, and --timestamp=plan uses the
+time the plan was created at (Terraform 1.5+ plans).
+
 Example:
   terraform-validator validate ./example/terraform.tfplan \
     --project my-project \
     --ancestry organization/my-org/folder/my-folder \
     --policy-path ./path/to/my/gcv/policies
+
+  terraform-validator validate --merge ./network.tfplan.json ./app.tfplan.json \
+    --policy-path ./path/to/my/gcv/policies
 `,
 	PreRunE: func(c *cobra.Command, args []string) error {
-		if len(args) != 1 {
+		if len(args) == 0 {
 			return errors.New("missing required argument <tfplan>")
 		}
+		if len(args) > 1 && !flags.validate.merge {
+			return errors.New("multiple <tfplan> arguments require --merge")
+		}
 		if flags.validate.offline && flags.validate.ancestry == "" {
 			return errors.New("please set ancestry via --ancestry in offline mode")
 		}
@@ -51,42 +114,170 @@ Example:
 	},
 	RunE: func(c *cobra.Command, args []string) error {
 		ctx := context.Background()
-		assets, err := tfgcv.ReadPlannedAssets(ctx, args[0], flags.validate.project, flags.validate.ancestry, flags.validate.offline)
+		report := &tfgcv.Report{}
+		opts, err := readOptions(flags.validate.readFlags, report)
 		if err != nil {
+			return err
+		}
+		// The plans are read once, for both their resource changes and
+		// their resource drift.
+		plans, err := tfgcv.ReadPlanFiles(args)
+		if err != nil {
+			return errors.Wrap(err, "converting tfplan to CAI assets")
+		}
+		assets, err := tfgcv.ConvertPlannedAssets(ctx, plans, flags.validate.project, flags.validate.ancestry, flags.validate.offline, opts...)
+		if err != nil && !isIncomplete(err) {
 			if errors.Cause(err) == tfgcv.ErrParsingProviderProject {
 				return errors.New("unable to parse provider project, please use --project flag")
 			}
 			return errors.Wrap(err, "converting tfplan to CAI assets")
 		}
+		printReport(os.Stderr, report)
+		incomplete := len(report.Errors) > 0
+		if flags.validate.strict {
+			if err := checkStrict(report); err != nil {
+				return err
+			}
+		}
 
 		auditResult, err := tfgcv.ValidateAssets(ctx, assets, flags.validate.policyPath)
 		if err != nil {
 			return errors.Wrap(err, "validating: FCV")
 		}
+		movedResult, err := tfgcv.SplitMovedViolations(ctx, auditResult, assets, flags.validate.policyPath)
+		if err != nil {
+			return errors.Wrap(err, "validating: FCV")
+		}
 
-		if len(auditResult.Violations) > 0 {
+		driftResult := &validator.AuditResponse{}
+		var driftAssets []google.Asset
+		if flags.validate.includeDrift {
+			driftReport := &tfgcv.Report{}
+			driftOpts, err := readOptions(flags.validate.readFlags, driftReport)
+			if err != nil {
+				return err
+			}
+			driftAssets, err = tfgcv.ConvertDriftedAssets(ctx, plans, flags.validate.project, flags.validate.ancestry, flags.validate.offline, driftOpts...)
+			if err != nil && !isIncomplete(err) {
+				return errors.Wrap(err, "converting resource drift to CAI assets")
+			}
+			printDriftReport(os.Stderr, driftReport)
+			if len(driftReport.Errors) > 0 {
+				incomplete = true
+			}
+			if flags.validate.strict {
+				if err := checkStrict(driftReport); err != nil {
+					return errors.Wrap(err, "resource drift")
+				}
+			}
+			driftResult, err = tfgcv.ValidateDriftedAssets(ctx, driftAssets, flags.validate.policyPath)
+			if err != nil {
+				return errors.Wrap(err, "validating resource drift: FCV")
+			}
+		}
+
+		// Violations in resource drift are traced back to the drifted
+		// assets only, not to the planned changes of the same resources.
+		allAssets := append(append([]google.Asset{}, assets...), driftAssets...)
+		var sourcePlans map[string][]string
+		if flags.validate.merge {
+			tfgcv.SetViolationSourcePlans(auditResult, assets)
+			tfgcv.SetViolationSourcePlans(movedResult, assets)
+			tfgcv.SetViolationSourcePlans(driftResult, allAssets)
+			sourcePlans = tfgcv.SourcePlansByAssetName(allAssets)
+		}
+		for _, result := range []*validator.AuditResponse{auditResult, movedResult} {
+			if err := tfgcv.SetViolationProvenance(result, assets); err != nil {
+				return errors.Wrap(err, "adding provenance to violations")
+			}
+		}
+		if err := tfgcv.SetViolationProvenance(driftResult, driftAssets); err != nil {
+			return errors.Wrap(err, "adding provenance to violations")
+		}
+		for _, result := range []*validator.AuditResponse{auditResult, movedResult, driftResult} {
+			tfgcv.SetViolationLocation(result, allAssets)
+		}
+		provenance := tfgcv.ProvenanceByAssetName(assets)
+		driftProvenance := tfgcv.ProvenanceByAssetName(driftAssets)
+		locations := tfgcv.LocationsByAssetName(allAssets)
+
+		if len(auditResult.Violations) > 0 || len(movedResult.Violations) > 0 || len(driftResult.Violations) > 0 {
 			if flags.validate.outputJSON {
+				auditResult.Violations = append(auditResult.Violations, movedResult.Violations...)
+				auditResult.Violations = append(auditResult.Violations, driftResult.Violations...)
 				marshaller := &jsonpb.Marshaler{}
 				if err := marshaller.Marshal(os.Stdout, auditResult); err != nil {
 					return errors.Wrap(err, "marshalling violations to json")
 				}
 			} else {
-				fmt.Print("Found Violations:\n\n")
-				for _, v := range auditResult.Violations {
-					fmt.Printf("Constraint %v on resource %v: %v\n\n",
-						v.Constraint,
-						v.Resource,
-						v.Message,
-					)
+				if len(auditResult.Violations) > 0 {
+					fmt.Print("Found Violations:\n\n")
+					printViolations(auditResult.Violations, sourcePlans, locations, provenance)
+				}
+				if len(movedResult.Violations) > 0 {
+					fmt.Print("Found Violations that existed before their resources were moved:\n\n")
+					printViolations(movedResult.Violations, sourcePlans, locations, provenance)
+				}
+				if len(driftResult.Violations) > 0 {
+					fmt.Print("Found Violations in resource drift (changes made outside of Terraform):\n\n")
+					printViolations(driftResult.Violations, sourcePlans, locations, driftProvenance)
 				}
 			}
 
+			if incomplete {
+				os.Exit(exitIncomplete)
+			}
 			os.Exit(2)
 		}
 
 		if !flags.validate.outputJSON {
 			fmt.Println("No violations found.")
 		}
+		if incomplete {
+			os.Exit(exitIncomplete)
+		}
 		return nil
 	},
 }
+
+// printViolations prints violations in text format. Each violation is
+// followed by the location of the violating resource, the plans (if
+// sourcePlans is set) and the terraform resources that contributed to it,
+// with the plan each of them was read from if sourcePlans is set.
+func printViolations(violations []*validator.Violation, sourcePlans map[string][]string, locations map[string]string, provenance map[string][]google.Provenance) {
+	for _, v := range violations {
+		fmt.Printf("Constraint %v on resource %v: %v\n",
+			v.Constraint,
+			v.Resource,
+			v.Message,
+		)
+		if location, ok := locations[v.Resource]; ok {
+			fmt.Printf("  Location: %v\n", location)
+		}
+		if plans, ok := sourcePlans[v.Resource]; ok {
+			fmt.Printf("  Source plans: %v\n", strings.Join(plans, ", "))
+		}
+		for _, p := range provenance[v.Resource] {
+			address := p.Address
+			if p.PreviousAddress != "" {
+				address = fmt.Sprintf("%s (moved from %s)", p.Address, p.PreviousAddress)
+			}
+			if sourcePlans != nil && p.SourcePlan != "" {
+				fmt.Printf("  Terraform resource: %v [%v] in %v\n", address, p.Action, p.SourcePlan)
+			} else {
+				fmt.Printf("  Terraform resource: %v [%v]\n", address, p.Action)
+			}
+			for _, f := range p.Fields {
+				if !strings.HasPrefix(f, "resource.data.") {
+					fmt.Printf("    %v\n", f)
+				}
+			}
+			for _, f := range p.Removed {
+				if !strings.HasPrefix(f, "resource.data.") {
+					fmt.Printf("    removed: %v\n", f)
+				}
+			}
+		}
+		fmt.Println()
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	accesscontextmanager "google.golang.org/genproto/googleapis/identity/accesscontextmanager/v1"
+)
+
+// AccessPolicy is an Access Context Manager access policy, the container of
+// the access levels and service perimeters of an organization.
+type AccessPolicy struct {
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+	Title  string `json:"title,omitempty"`
+}
+
+// AccessLevel describes the conditions under which requests are allowed.
+type AccessLevel struct {
+	Name        string       `json:"name"`
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Basic       *BasicLevel  `json:"basic,omitempty"`
+	Custom      *CustomLevel `json:"custom,omitempty"`
+}
+
+// BasicLevel is an access level granted when all ("AND") or any ("OR") of
+// its conditions are met.
+type BasicLevel struct {
+	Conditions        []AccessLevelCondition `json:"conditions,omitempty"`
+	CombiningFunction string                 `json:"combining_function,omitempty"`
+}
+
+// AccessLevelCondition is met when all of its attributes are met.
+type AccessLevelCondition struct {
+	IPSubnetworks        []string      `json:"ip_subnetworks,omitempty"`
+	DevicePolicy         *DevicePolicy `json:"device_policy,omitempty"`
+	RequiredAccessLevels []string      `json:"required_access_levels,omitempty"`
+	Negate               bool          `json:"negate,omitempty"`
+	Members              []string      `json:"members,omitempty"`
+	Regions              []string      `json:"regions,omitempty"`
+}
+
+// DevicePolicy restricts the devices requests may come from.
+type DevicePolicy struct {
+	RequireScreenlock             bool           `json:"require_screenlock,omitempty"`
+	AllowedEncryptionStatuses     []string       `json:"allowed_encryption_statuses,omitempty"`
+	OsConstraints                 []OsConstraint `json:"os_constraints,omitempty"`
+	AllowedDeviceManagementLevels []string       `json:"allowed_device_management_levels,omitempty"`
+	RequireAdminApproval          bool           `json:"require_admin_approval,omitempty"`
+	RequireCorpOwned              bool           `json:"require_corp_owned,omitempty"`
+}
+
+// OsConstraint restricts the operating system of a device.
+type OsConstraint struct {
+	OsType                  string `json:"os_type"`
+	MinimumVersion          string `json:"minimum_version,omitempty"`
+	RequireVerifiedChromeOs bool   `json:"require_verified_chrome_os,omitempty"`
+}
+
+// CustomLevel is an access level granted when a CEL expression is true.
+type CustomLevel struct {
+	Expr *Expr `json:"expr,omitempty"`
+}
+
+// Expr is a CEL expression.
+type Expr struct {
+	Expression  string `json:"expression"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+// ServicePerimeter restricts the services that resources inside the
+// perimeter can be accessed with from outside the perimeter.
+type ServicePerimeter struct {
+	Name                  string                  `json:"name"`
+	Title                 string                  `json:"title,omitempty"`
+	Description           string                  `json:"description,omitempty"`
+	PerimeterType         string                  `json:"perimeter_type,omitempty"`
+	Status                *ServicePerimeterConfig `json:"status,omitempty"`
+	Spec                  *ServicePerimeterConfig `json:"spec,omitempty"`
+	UseExplicitDryRunSpec bool                    `json:"use_explicit_dry_run_spec,omitempty"`
+}
+
+// ServicePerimeterConfig is the enforced (status) or dry run (spec)
+// configuration of a service perimeter.
+type ServicePerimeterConfig struct {
+	Resources             []string               `json:"resources,omitempty"`
+	AccessLevels          []string               `json:"access_levels,omitempty"`
+	RestrictedServices    []string               `json:"restricted_services,omitempty"`
+	VpcAccessibleServices *VpcAccessibleServices `json:"vpc_accessible_services,omitempty"`
+}
+
+// VpcAccessibleServices restricts the services accessible from the VPC
+// networks of a service perimeter.
+type VpcAccessibleServices struct {
+	EnableRestriction bool     `json:"enable_restriction,omitempty"`
+	AllowedServices   []string `json:"allowed_services,omitempty"`
+}
+
+const (
+	accessPolicyAssetType     = "accesscontextmanager.googleapis.com/AccessPolicy"
+	accessLevelAssetType      = "accesscontextmanager.googleapis.com/AccessLevel"
+	servicePerimeterAssetType = "accesscontextmanager.googleapis.com/ServicePerimeter"
+)
+
+// accessContextManagerMappers returns the mappers of the
+// google_access_context_manager_* resources. The conversion library converts
+// them into resources in the format of the Access Context Manager API, which
+// augmentAsset moves to the AccessPolicy, AccessLevel and ServicePerimeter
+// fields of the asset.
+//
+// Access Context Manager resources are not fetched, so a
+// google_access_context_manager_service_perimeter_resource only adds its
+// resource to a perimeter in the same plan, or to an otherwise empty one.
+func accessContextManagerMappers() map[string][]converter.Mapper {
+	return map[string][]converter.Mapper{
+		"google_access_context_manager_access_policy": {{
+			Convert: converter.GetAccessContextManagerAccessPolicyCaiObject,
+		}},
+		"google_access_context_manager_access_level": {{
+			Convert: converter.GetAccessContextManagerAccessLevelCaiObject,
+		}},
+		"google_access_context_manager_access_levels": {{
+			Convert: convertAccessLevels,
+		}},
+		"google_access_context_manager_service_perimeter": {{
+			Convert:           converter.GetAccessContextManagerServicePerimeterCaiObject,
+			MergeCreateUpdate: mergeServicePerimeter,
+		}},
+		"google_access_context_manager_service_perimeters": {{
+			Convert:           convertServicePerimeters,
+			MergeCreateUpdate: mergeServicePerimeter,
+		}},
+		"google_access_context_manager_service_perimeter_resource": {{
+			Convert:           convertServicePerimeterResource,
+			MergeCreateUpdate: mergeServicePerimeterResource,
+			MergeDelete:       mergeDeleteServicePerimeterResource,
+		}},
+	}
+}
+
+// convertAccessLevels converts a google_access_context_manager_access_levels
+// resource into one asset per access level.
+func convertAccessLevels(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	obj, err := converter.GetAccessContextManagerAccessLevelsApiObject(d, config)
+	if err != nil {
+		return nil, err
+	}
+	return accessContextAssets(accessLevelAssetType, "AccessLevel", obj["accessLevels"])
+}
+
+// convertServicePerimeters converts a
+// google_access_context_manager_service_perimeters resource into one asset
+// per service perimeter.
+func convertServicePerimeters(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	obj, err := converter.GetAccessContextManagerServicePerimetersApiObject(d, config)
+	if err != nil {
+		return nil, err
+	}
+	return accessContextAssets(servicePerimeterAssetType, "ServicePerimeter", obj["servicePerimeters"])
+}
+
+func accessContextAssets(assetType, discoveryName string, items interface{}) ([]converter.Asset, error) {
+	list, _ := items.([]interface{})
+	if len(list) == 0 {
+		return nil, converter.ErrNoConversion
+	}
+	var assets []converter.Asset
+	for _, item := range list {
+		data, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := data["name"].(string)
+		assets = append(assets, accessContextAsset(assetType, discoveryName, name, data))
+	}
+	return assets, nil
+}
+
+// accessContextAsset returns an asset holding an Access Context Manager API
+// object, in the same format as the conversion library.
+func accessContextAsset(assetType, discoveryName, name string, data map[string]interface{}) converter.Asset {
+	return converter.Asset{
+		Name: "//accesscontextmanager.googleapis.com/" + name,
+		Type: assetType,
+		Resource: &converter.AssetResource{
+			Version:              "v1",
+			DiscoveryDocumentURI: "https://www.googleapis.com/discovery/v1/apis/accesscontextmanager/v1/rest",
+			DiscoveryName:        discoveryName,
+			Data:                 data,
+		},
+	}
+}
+
+// convertServicePerimeterResource converts a
+// google_access_context_manager_service_perimeter_resource into a perimeter
+// holding only its resource. Perimeters always have a title, perimeter
+// resources never do.
+func convertServicePerimeterResource(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	perimeter, ok := d.GetOk("perimeter_name")
+	if !ok {
+		return nil, fmt.Errorf("required field 'perimeter_name' is not set")
+	}
+	resource, ok := d.GetOk("resource")
+	if !ok {
+		return nil, fmt.Errorf("required field 'resource' is not set")
+	}
+	return []converter.Asset{accessContextAsset(servicePerimeterAssetType, "ServicePerimeter", perimeter.(string), map[string]interface{}{
+		"name": perimeter.(string),
+		"status": map[string]interface{}{
+			"resources": []interface{}{resource.(string)},
+		},
+	})}, nil
+}
+
+// isPerimeterResource reports whether an asset was converted from a
+// google_access_context_manager_service_perimeter_resource.
+func isPerimeterResource(cai converter.Asset) bool {
+	if cai.Type != servicePerimeterAssetType || cai.Resource == nil {
+		return false
+	}
+	_, ok := cai.Resource.Data["title"]
+	return !ok
+}
+
+// perimeterResources returns the resources enforced by a service perimeter.
+func perimeterResources(cai converter.Asset) []string {
+	if cai.Resource == nil {
+		return nil
+	}
+	status, _ := cai.Resource.Data["status"].(map[string]interface{})
+	var resources []string
+	if list, ok := status["resources"].([]interface{}); ok {
+		for _, r := range list {
+			if s, ok := r.(string); ok {
+				resources = append(resources, s)
+			}
+		}
+	}
+	return resources
+}
+
+// withPerimeterResources returns a copy of a service perimeter enforcing the
+// given resources.
+func withPerimeterResources(cai converter.Asset, resources []string) converter.Asset {
+	data := make(map[string]interface{}, len(cai.Resource.Data))
+	for k, v := range cai.Resource.Data {
+		data[k] = v
+	}
+	status := make(map[string]interface{})
+	if existing, ok := data["status"].(map[string]interface{}); ok {
+		for k, v := range existing {
+			status[k] = v
+		}
+	}
+	sort.Strings(resources)
+	list := make([]interface{}, len(resources))
+	for i, r := range resources {
+		list[i] = r
+	}
+	if len(list) == 0 {
+		delete(status, "resources")
+	} else {
+		status["resources"] = list
+	}
+	data["status"] = status
+
+	resource := *cai.Resource
+	resource.Data = data
+	cai.Resource = &resource
+	return cai
+}
+
+// mergeServicePerimeter replaces a service perimeter, keeping the resources
+// added to it by perimeter resources.
+func mergeServicePerimeter(existing, incoming converter.Asset) converter.Asset {
+	resources := perimeterResources(incoming)
+	for _, r := range perimeterResources(existing) {
+		if !containsString(resources, r) {
+			resources = append(resources, r)
+		}
+	}
+	return withPerimeterResources(incoming, resources)
+}
+
+// mergeServicePerimeterResource adds the resource of incoming to the
+// resources of the existing perimeter.
+func mergeServicePerimeterResource(existing, incoming converter.Asset) converter.Asset {
+	resources := perimeterResources(existing)
+	for _, r := range perimeterResources(incoming) {
+		if !containsString(resources, r) {
+			resources = append(resources, r)
+		}
+	}
+	return withPerimeterResources(existing, resources)
+}
+
+// mergeDeleteServicePerimeterResource removes the resource of incoming from
+// the resources of the existing perimeter.
+func mergeDeleteServicePerimeterResource(existing, incoming converter.Asset) converter.Asset {
+	deleted := perimeterResources(incoming)
+	var resources []string
+	for _, r := range perimeterResources(existing) {
+		if !containsString(deleted, r) {
+			resources = append(resources, r)
+		}
+	}
+	return withPerimeterResources(existing, resources)
+}
+
+// setAccessContextPolicy moves the Access Context Manager API object of an
+// asset converted from a google_access_context_manager_* resource to its
+// AccessPolicy, AccessLevel or ServicePerimeter field. It reports whether
+// the asset holds such an object.
+func setAccessContextPolicy(asset *Asset, cai converter.Asset) (bool, error) {
+	if cai.Resource == nil {
+		return false, nil
+	}
+	// The API objects of access policies have no name, which the API
+	// assigns.
+	data := map[string]interface{}{
+		"name": strings.TrimPrefix(cai.Name, "//accesscontextmanager.googleapis.com/"),
+	}
+	for k, v := range cai.Resource.Data {
+		data[k] = v
+	}
+	var err error
+	switch cai.Type {
+	case accessPolicyAssetType:
+		asset.AccessPolicy = &AccessPolicy{}
+		err = decodeAccessContextPolicy(data, &accesscontextmanager.AccessPolicy{}, asset.AccessPolicy)
+	case accessLevelAssetType:
+		asset.AccessLevel = &AccessLevel{}
+		err = decodeAccessContextPolicy(data, &accesscontextmanager.AccessLevel{}, asset.AccessLevel)
+		if b := asset.AccessLevel.Basic; b != nil && b.CombiningFunction == "" {
+			b.CombiningFunction = accesscontextmanager.BasicLevel_AND.String()
+		}
+	case servicePerimeterAssetType:
+		asset.ServicePerimeter = &ServicePerimeter{}
+		err = decodeAccessContextPolicy(data, &accesscontextmanager.ServicePerimeter{}, asset.ServicePerimeter)
+		if asset.ServicePerimeter.PerimeterType == "" {
+			asset.ServicePerimeter.PerimeterType = accesscontextmanager.ServicePerimeter_PERIMETER_TYPE_REGULAR.String()
+		}
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, fmt.Errorf("decoding %s: %w", cai.Type, err)
+	}
+	return true, nil
+}
+
+// decodeAccessContextPolicy decodes an Access Context Manager API object
+// into v through its proto message msg, which drops the fields GCV does not
+// support (e.g. ingress and egress policies). Enums set to their default
+// value are left out.
+func decodeAccessContextPolicy(data map[string]interface{}, msg proto.Message, v interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(b), msg); err != nil {
+		return err
+	}
+	s, err := (&jsonpb.Marshaler{OrigName: true}).MarshalToString(msg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(s), v)
+}
@@ -0,0 +1,161 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPerimeter = "accessPolicies/123/servicePerimeters/restrict_storage"
+
+func TestAddResourceChanges_servicePerimeter(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_access_context_manager_service_perimeter_resource", "other", create, map[string]interface{}{
+			"perimeter_name": testPerimeter,
+			"resource":       "projects/789",
+		}),
+		newTestResourceChange("google_access_context_manager_service_perimeter", "storage", create, map[string]interface{}{
+			"parent":         "accessPolicies/123",
+			"name":           testPerimeter,
+			"title":          "restrict_storage",
+			"perimeter_type": "PERIMETER_TYPE_REGULAR",
+			"status": []interface{}{map[string]interface{}{
+				"resources":           []interface{}{"projects/456"},
+				"restricted_services": []interface{}{"storage.googleapis.com"},
+				"vpc_accessible_services": []interface{}{map[string]interface{}{
+					"enable_restriction": true,
+					"allowed_services":   []interface{}{"RESTRICTED-SERVICES"},
+				}},
+				"ingress_policies": []interface{}{map[string]interface{}{
+					"ingress_from": []interface{}{map[string]interface{}{"identity_type": "ANY_IDENTITY"}},
+				}},
+			}},
+		}),
+		newTestResourceChange("google_access_context_manager_service_perimeter_resource", "extra", create, map[string]interface{}{
+			"perimeter_name": testPerimeter,
+			"resource":       "projects/321",
+		}),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//accesscontextmanager.googleapis.com/"+testPerimeter, assets[0].Name)
+	assert.Equal(t, "accesscontextmanager.googleapis.com/ServicePerimeter", assets[0].Type)
+	assert.Nil(t, assets[0].Resource)
+	assert.Equal(t, &ServicePerimeter{
+		Name:          testPerimeter,
+		Title:         "restrict_storage",
+		PerimeterType: "PERIMETER_TYPE_REGULAR",
+		Status: &ServicePerimeterConfig{
+			Resources:          []string{"projects/321", "projects/456", "projects/789"},
+			RestrictedServices: []string{"storage.googleapis.com"},
+			VpcAccessibleServices: &VpcAccessibleServices{
+				EnableRestriction: true,
+				AllowedServices:   []string{"RESTRICTED-SERVICES"},
+			},
+		},
+	}, assets[0].ServicePerimeter)
+	require.Len(t, assets[0].Provenance, 3)
+	assert.Equal(t, []string{`service_perimeter.status.resources["projects/789"]`}, assets[0].Provenance[0].Fields)
+	assert.Equal(t, []string{"service_perimeter"}, assets[0].Provenance[1].Fields)
+
+	// Deleting a perimeter resource removes its resource from the perimeter.
+	del := newTestResourceChange("google_access_context_manager_service_perimeter_resource", "extra", tfjson.Actions{"delete"}, map[string]interface{}{
+		"perimeter_name": testPerimeter,
+		"resource":       "projects/321",
+	})
+	rd, err := c.newResourceData(del, del.Change.Before)
+	require.NoError(t, err)
+	mapper := c.mapperFuncs["google_access_context_manager_service_perimeter_resource"][0]
+	deleted, err := mapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	augmented, err := c.augmentAsset(rd, c.cfg, mapper.MergeDelete(assets[0].converterAsset, deleted[0]))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"projects/456", "projects/789"}, augmented.ServicePerimeter.Status.Resources)
+	assert.Equal(t, "restrict_storage", augmented.ServicePerimeter.Title)
+}
+
+func TestAddResourceChanges_accessLevels(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_access_context_manager_access_policy", "policy", create, map[string]interface{}{
+			"name":   "123",
+			"parent": "organizations/456",
+			"title":  "my policy",
+		}),
+		newTestResourceChange("google_access_context_manager_access_level", "corp", create, map[string]interface{}{
+			"parent": "accessPolicies/123",
+			"name":   "accessPolicies/123/accessLevels/corp",
+			"title":  "corp",
+			"basic": []interface{}{map[string]interface{}{
+				"combining_function": "OR",
+				"conditions": []interface{}{map[string]interface{}{
+					"ip_subnetworks": []interface{}{"10.0.0.0/8"},
+					"device_policy": []interface{}{map[string]interface{}{
+						"require_screen_lock": true,
+						"os_constraints":      []interface{}{map[string]interface{}{"os_type": "DESKTOP_CHROME_OS"}},
+					}},
+				}},
+			}},
+		}),
+		newTestResourceChange("google_access_context_manager_access_levels", "levels", create, map[string]interface{}{
+			"parent": "accessPolicies/123",
+			"access_levels": []interface{}{
+				map[string]interface{}{
+					"name":   "accessPolicies/123/accessLevels/us",
+					"title":  "us",
+					"custom": []interface{}{map[string]interface{}{"expr": []interface{}{map[string]interface{}{"expression": `origin.region_code == "US"`}}}},
+				},
+			},
+		}),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 3)
+	assert.Equal(t, "//accesscontextmanager.googleapis.com/accessPolicies/123", assets[0].Name)
+	assert.Equal(t, &AccessPolicy{Name: "accessPolicies/123", Parent: "organizations/456", Title: "my policy"}, assets[0].AccessPolicy)
+	assert.Equal(t, "//accesscontextmanager.googleapis.com/accessPolicies/123/accessLevels/corp", assets[1].Name)
+	assert.Equal(t, &AccessLevel{
+		Name:  "accessPolicies/123/accessLevels/corp",
+		Title: "corp",
+		Basic: &BasicLevel{
+			CombiningFunction: "OR",
+			Conditions: []AccessLevelCondition{{
+				IPSubnetworks: []string{"10.0.0.0/8"},
+				DevicePolicy: &DevicePolicy{
+					RequireScreenlock: true,
+					OsConstraints:     []OsConstraint{{OsType: "DESKTOP_CHROME_OS"}},
+				},
+			}},
+		},
+	}, assets[1].AccessLevel)
+	assert.Equal(t, "accesscontextmanager.googleapis.com/AccessLevel", assets[2].Type)
+	assert.Equal(t, &AccessLevel{
+		Name:   "accessPolicies/123/accessLevels/us",
+		Title:  "us",
+		Custom: &CustomLevel{Expr: &Expr{Expression: `origin.region_code == "US"`}},
+	}, assets[2].AccessLevel)
+}
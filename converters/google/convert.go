@@ -44,6 +44,33 @@ type Asset struct {
 	Resource  *AssetResource `json:"resource,omitempty"`
 	IAMPolicy *IAMPolicy     `json:"iam_policy,omitempty"`
 	OrgPolicy []*OrgPolicy   `json:"org_policy,omitempty"`
+	// V2OrgPolicies are the organization policies of the v2 API set on
+	// the asset.
+	V2OrgPolicies []*V2OrgPolicy `json:"v2_org_policies,omitempty"`
+	// At most one of AccessPolicy, AccessLevel and ServicePerimeter is set,
+	// on assets converted from google_access_context_manager_* resources.
+	AccessPolicy     *AccessPolicy     `json:"access_policy,omitempty"`
+	AccessLevel      *AccessLevel      `json:"access_level,omitempty"`
+	ServicePerimeter *ServicePerimeter `json:"service_perimeter,omitempty"`
+	// Ancestors lists the resource hierarchy of the asset from its closest
+	// ancestor to the organization, as in CAI exports. Ancestry is the
+	// same hierarchy in the reverse order.
+	Ancestors []string `json:"ancestors,omitempty"`
+	// UpdateTime is the time the asset was converted at (see
+	// Converter.SetClock).
+	UpdateTime *Timestamp `json:"update_time,omitempty"`
+	// Deleted marks the tombstone of a resource deleted or replaced by the
+	// plan (see Converter.SetDeletions).
+	Deleted bool `json:"deleted,omitempty"`
+	// SourcePlans lists the plans whose resource changes contributed to
+	// the asset (see Converter.AddPlanResourceChanges).
+	SourcePlans []string `json:"-"`
+	// Provenance lists the resource changes that contributed to the asset.
+	Provenance []Provenance `json:"-"`
+	// Prior is the asset converted from the values before the change of the
+	// resources the plan moved and updated, if any (see
+	// Converter.AddPlanMoves).
+	Prior *Asset `json:"-"`
 	// Store the converter's version of the asset to allow for merges which
 	// operate on this type. When matching json tags land in the conversions
 	// library, this could be nested to avoid the duplication of fields.
@@ -52,13 +79,17 @@ type Asset struct {
 
 // IAMPolicy is the representation of a Cloud IAM policy set on a cloud resource.
 type IAMPolicy struct {
-	Bindings []IAMBinding `json:"bindings"`
+	Bindings     []IAMBinding  `json:"bindings"`
+	AuditConfigs []AuditConfig `json:"audit_configs,omitempty"`
 }
 
-// IAMBinding binds a role to a set of members.
+// IAMBinding binds a role to a set of members, if the condition is met.
+// Bindings with the same role and different conditions are separate
+// bindings.
 type IAMBinding struct {
-	Role    string   `json:"role"`
-	Members []string `json:"members"`
+	Role      string        `json:"role"`
+	Members   []string      `json:"members"`
+	Condition *IAMCondition `json:"condition,omitempty"`
 }
 
 // AssetResource is nested within the Asset type.
@@ -68,6 +99,7 @@ type AssetResource struct {
 	DiscoveryName        string                 `json:"discovery_name"`
 	Parent               string                 `json:"parent"`
 	Data                 map[string]interface{} `json:"data"`
+	Location             string                 `json:"location,omitempty"`
 }
 
 //OrgPolicy is for managing organization policies.
@@ -126,6 +158,22 @@ type BooleanPolicy struct {
 type RestoreDefault struct {
 }
 
+// mappers returns the mappers of the conversion library and of the resource
+// types it does not support yet.
+func mappers() map[string][]converter.Mapper {
+	m := converter.Mappers()
+	for kind, ms := range auditConfigMappers() {
+		m[kind] = ms
+	}
+	for kind, ms := range accessContextManagerMappers() {
+		m[kind] = ms
+	}
+	for kind, ms := range v2OrgPolicyMappers() {
+		m[kind] = ms
+	}
+	return m
+}
+
 // NewConverter is a factory function for Converter.
 func NewConverter(ctx context.Context, ancestryManager ancestrymanager.AncestryManager, project string, offline bool) (*Converter, error) {
 	cfg := &converter.Config{
@@ -141,6 +189,18 @@ func NewConverter(ctx context.Context, ancestryManager ancestrymanager.AncestryM
 	cfg.AccessToken = multiEnvSearch([]string{
 		"GOOGLE_OAUTH_ACCESS_TOKEN",
 	})
+
+	// Search for the default region and zone of resources
+	cfg.Region = multiEnvSearch([]string{
+		"GOOGLE_REGION",
+		"GCLOUD_REGION",
+		"CLOUDSDK_COMPUTE_REGION",
+	})
+	cfg.Zone = multiEnvSearch([]string{
+		"GOOGLE_ZONE",
+		"GCLOUD_ZONE",
+		"CLOUDSDK_COMPUTE_ZONE",
+	})
 	if !offline {
 		converter.ConfigureBasePaths(cfg)
 		if err := cfg.LoadAndValidate(ctx); err != nil {
@@ -149,12 +209,18 @@ func NewConverter(ctx context.Context, ancestryManager ancestrymanager.AncestryM
 	}
 
 	return &Converter{
+		envRegion:       cfg.Region,
+		envZone:         cfg.Zone,
 		schema:          provider.Provider(),
-		mapperFuncs:     converter.Mappers(),
+		mapperFuncs:     withIAMDetails(mappers()),
 		offline:         offline,
 		cfg:             cfg,
 		ancestryManager: ancestryManager,
 		assets:          make(map[string]Asset),
+		moves:           make(map[string]map[string]string),
+		priors:          make(map[string]Asset),
+		streamed:        make(map[string]bool),
+		clock:           time.Now,
 	}, nil
 }
 
@@ -163,6 +229,14 @@ func NewConverter(ctx context.Context, ancestryManager ancestrymanager.AncestryM
 type Converter struct {
 	schema *schema.Provider
 
+	// Schemas used to read the values of resource types instead of their
+	// conversion schema, see SetProviderSchemas.
+	readSchemas map[string]map[string]*schema.Schema
+
+	// Attributes unknown to the conversion schema, by resource type and
+	// attribute.
+	drift map[string]*SchemaDrift
+
 	// Map terraform resource kinds (i.e. "google_compute_instance")
 	// to their mapping/merging functions.
 	mapperFuncs map[string][]converter.Mapper
@@ -170,11 +244,53 @@ type Converter struct {
 	offline bool
 	cfg     *converter.Config
 
+	// Default region and zone of the environment, see SetProviderDefaults.
+	envRegion string
+	envZone   string
+
 	// ancestryManager provides a manager to find the ancestry information for a project.
 	ancestryManager ancestrymanager.AncestryManager
 
 	// Map of converted assets (key = asset.Type + asset.Name)
 	assets map[string]Asset
+
+	// Resource addresses moved by each plan, mapped to their previous
+	// address (key = plan, then new address), see AddPlanMoves.
+	moves map[string]map[string]string
+
+	// Assets converted from the values of moved resources before the
+	// change (key = asset.Type + asset.Name), see Asset.Prior.
+	priors map[string]Asset
+
+	// Outcome of every resource change added to the converter.
+	coverage []ResourceCoverage
+
+	// Transformers run on every augmented asset, see AddTransformers.
+	transformers []Transformer
+
+	// Returns the update time of converted assets and org policies, see
+	// SetClock, and its first result, which every asset shares.
+	clock func() time.Time
+	now   time.Time
+
+	// Whether to keep converting after a resource change failed to convert,
+	// and the failures recorded in that case.
+	continueOnError bool
+	errors          []*ResourceError
+
+	// Whether deleted resources are converted into tombstones, see
+	// SetDeletions.
+	deletions bool
+
+	// The error of the last failed plugin merge, see Converter.merge.
+	mergeErr error
+
+	// Receives the assets no later resource change can merge into, see
+	// SetStream, with the keys of the ones converted by the current
+	// resource change and of the ones already streamed.
+	stream   func(Asset) error
+	final    []string
+	streamed map[string]bool
 }
 
 // Schemas exposes the schemas of resources this converter knows about.
@@ -188,11 +304,51 @@ func (c *Converter) Schemas() map[string]*schema.Resource {
 	return supported
 }
 
+// SetProviderDefaults sets the default region and zone of the google provider
+// for the resource changes added from then on, e.g. the ones of the provider
+// configuration of a plan. An empty region or zone falls back to the default
+// of the environment (GOOGLE_REGION, GOOGLE_ZONE, ...).
+func (c *Converter) SetProviderDefaults(region, zone string) {
+	c.cfg.Region = region
+	if region == "" {
+		c.cfg.Region = c.envRegion
+	}
+	c.cfg.Zone = zone
+	if zone == "" {
+		c.cfg.Zone = c.envZone
+	}
+}
+
+// SetClock sets the function returning the update time of the assets and org
+// policies converted from then on. It defaults to time.Now; a fixed time makes the
+// converted assets reproducible. The clock is read once, when the first of
+// these assets is converted, so that they all share the same update time.
+func (c *Converter) SetClock(clock func() time.Time) {
+	c.clock = clock
+	c.now = time.Time{}
+}
+
+// updateTime returns the update time of converted assets, see SetClock.
+func (c *Converter) updateTime() time.Time {
+	if c.now.IsZero() {
+		c.now = c.clock().UTC()
+	}
+	return c.now
+}
+
 // Compatibility shim: maintain support for ComposeTF12Resources -> AddResource pipeline
 func (c *Converter) AddResource(rc *tfjson.ResourceChange) error {
 	return c.AddResourceChanges([]*tfjson.ResourceChange{rc})
 }
 
+// AddPlanResourceChanges is like AddResourceChanges, but attributes the
+// resulting assets to the named plan. Calling it for several plans merges
+// all of their resource changes into one set of assets, and overlapping
+// assets are resolved by the same merge logic as within a single plan.
+func (c *Converter) AddPlanResourceChanges(plan string, changes []*tfjson.ResourceChange) error {
+	return c.addResourceChanges(plan, changes)
+}
+
 // AddResourceChange processes the resource changes in two stages:
 // 1. Process deletions (fetching canonical resources from GCP as necessary)
 // 2. Process creates and updates (fetching canonical resources from GCP as necessary)
@@ -200,64 +356,149 @@ func (c *Converter) AddResource(rc *tfjson.ResourceChange) error {
 // an IAM Binding and Member conflict with each other, but one is replacing the
 // other.
 func (c *Converter) AddResourceChanges(changes []*tfjson.ResourceChange) error {
+	return c.addResourceChanges("", changes)
+}
+
+func (c *Converter) addResourceChanges(plan string, changes []*tfjson.ResourceChange) error {
 	var createOrUpdates []*tfjson.ResourceChange
 	for _, rc := range changes {
 		// skip unknown resources
-		if _, ok := c.schema.ResourcesMap[rc.Type]; !ok {
+		if !c.hasSchema(rc.Type) {
 			glog.Infof("unknown resource: %s", rc.Type)
+			if strings.HasPrefix(rc.Type, "google_") {
+				c.recordCoverage(plan, rc, nil, CoverageUnsupported, "unknown to the google provider schema", nil)
+			} else {
+				c.recordCoverage(plan, rc, nil, CoverageNonGoogle, "not a google provider resource", nil)
+			}
 			continue
 		}
 
 		// Skip unsupported resources
 		if _, ok := c.mapperFuncs[rc.Type]; !ok {
 			glog.Infof("unsupported resource: %s", rc.Type)
+			c.recordCoverage(plan, rc, nil, CoverageUnsupported, "no converter for resource type", nil)
 			continue
 		}
 
-		if tfplan.IsCreate(rc) || tfplan.IsUpdate(rc) || tfplan.IsDeleteCreate(rc) {
+		if previous, ok := c.moves[plan][rc.Address]; ok {
+			glog.Infof("resource moved: %s -> %s", previous, rc.Address)
+			if tfplan.IsUpdate(rc) {
+				// The prior assets only label violations, a resource
+				// that cannot be converted before the move is validated
+				// as a plain update.
+				if err := c.addPrior(rc); err != nil {
+					glog.Warningf("converting %s before the move: %v", rc.Address, err)
+				}
+			}
+		}
+
+		if tfplan.IsCreate(rc) || tfplan.IsUpdate(rc) || tfplan.IsDeleteCreate(rc) || tfplan.IsCreateDelete(rc) {
 			createOrUpdates = append(createOrUpdates, rc)
 		} else if tfplan.IsDelete(rc) {
-			if err := c.addDelete(rc); err != nil {
-				return fmt.Errorf("adding resource deletion %w", err)
+			rd, err := c.newResourceData(rc, rc.Change.Before)
+			var names []string
+			if err == nil {
+				names, err = c.addDelete(plan, rc, rd)
 			}
+			if err == nil && c.deletions {
+				var tombstones []string
+				tombstones, err = c.addTombstones(plan, rc, rd)
+				names = append(names, tombstones...)
+			}
+			if err != nil {
+				c.recordCoverage(plan, rc, rd, CoverageError, err.Error(), nil)
+				if err := c.handleResourceError(plan, rc, "adding resource deletion", err); err != nil {
+					return err
+				}
+			} else if len(names) == 0 {
+				c.recordCoverage(plan, rc, rd, CoverageSkipped, "deletion does not change any other asset", nil)
+			} else {
+				c.recordCoverage(plan, rc, rd, CoverageConverted, "", names)
+			}
+		} else {
+			c.recordCoverage(plan, rc, nil, CoverageSkipped, fmt.Sprintf("%s action", tfplan.ActionOf(rc)), nil)
 		}
 	}
 
 	for _, rc := range createOrUpdates {
-		if err := c.addCreateOrUpdate(rc); err != nil {
+		if err := c.streamAssets(); err != nil {
+			return err
+		}
+		rd, err := c.newResourceData(rc, rc.Change.After)
+		var names []string
+		if err == nil {
+			names, err = c.addCreateOrUpdate(plan, rc, rd)
+		}
+		if err == nil && c.deletions && tfplan.ActionOf(rc).IsReplace() {
+			// The replaced resource is deleted as well.
+			var before *FakeResourceData
+			before, err = c.newResourceData(rc, rc.Change.Before)
+			if err == nil {
+				var tombstones []string
+				tombstones, err = c.addTombstones(plan, rc, before)
+				names = append(names, tombstones...)
+			}
+		}
+		if err != nil {
 			if errorssyslib.Is(err, ErrDuplicateAsset) {
 				glog.Warningf("adding resource change: %v", err)
+				c.recordCoverage(plan, rc, rd, CoverageSkipped, err.Error(), names)
 			} else {
-				return fmt.Errorf("adding resource create or update %w", err)
+				c.recordCoverage(plan, rc, rd, CoverageError, err.Error(), names)
+				if err := c.handleResourceError(plan, rc, "adding resource create or update", err); err != nil {
+					return err
+				}
 			}
+			continue
+		}
+		if len(names) == 0 {
+			c.recordCoverage(plan, rc, rd, CoverageUnsupported, "converter produced no assets", nil)
+		} else {
+			c.recordCoverage(plan, rc, rd, CoverageConverted, "", names)
 		}
 	}
 
-	return nil
+	return c.streamAssets()
+}
+
+// newResourceData creates resource data from the values (before or after) of
+// a resource change. The values before the change are the prior values of
+// the resource data, if the resource exists.
+func (c *Converter) newResourceData(rc *tfjson.ResourceChange, values interface{}) (*FakeResourceData, error) {
+	m, ok := values.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("reading resource values: expected an object, got %T", values)
+	}
+	prior, _ := rc.Change.Before.(map[string]interface{})
+	rd, err := NewFakeResourceDataWithPrior(rc.Type, c.resourceSchema(rc.Type, m), prior, m)
+	if err != nil {
+		return nil, fmt.Errorf("reading resource values: %w", err)
+	}
+	c.recordSchemaDrift(rc, m, rd.DroppedAttributes())
+	rd.before = prior
+	rd.after, _ = rc.Change.After.(map[string]interface{})
+	return &rd, nil
 }
 
 // For deletions, we only need to handle mappers that support
-// both fetch and mergeDelete. Supporting just one doesn't
-// make sense, and supporting neither means that the deletion
-// can just happen without needing to be merged.
-func (c *Converter) addDelete(rc *tfjson.ResourceChange) error {
-	resource, _ := c.schema.ResourcesMap[rc.Type]
-	rd := NewFakeResourceData(
-		rc.Type,
-		resource.Schema,
-		rc.Change.Before.(map[string]interface{}),
-	)
+// mergeDelete: supporting neither means that the deletion can just
+// happen without needing to be merged. The deletion is merged into the
+// asset converted from an earlier plan or, if the mapper supports fetch,
+// into the asset fetched from GCP.
+// It returns the names of the assets the deletion was merged into.
+func (c *Converter) addDelete(plan string, rc *tfjson.ResourceChange, rd *FakeResourceData) ([]string, error) {
+	var names []string
 	for _, mapper := range c.mapperFuncs[rd.Kind()] {
-		if mapper.Fetch == nil || mapper.MergeDelete == nil {
+		if mapper.MergeDelete == nil {
 			continue
 		}
-		convertedItems, err := mapper.Convert(&rd, c.cfg)
+		convertedItems, err := mapper.Convert(rd, c.cfg)
 
 		if err != nil {
 			if errors.Cause(err) == converter.ErrNoConversion {
 				continue
 			}
-			return errors.Wrap(err, "converting asset")
+			return names, errors.Wrap(err, "converting asset")
 		}
 
 		for _, converted := range convertedItems {
@@ -265,65 +506,81 @@ func (c *Converter) addDelete(rc *tfjson.ResourceChange) error {
 			key := converted.Type + converted.Name
 			var existingConverterAsset *converter.Asset
 			if existing, exists := c.assets[key]; exists {
+				if existing.Deleted {
+					// The whole asset is deleted already.
+					continue
+				}
 				existingConverterAsset = &existing.converterAsset
-			} else if !c.offline {
-				asset, err := mapper.Fetch(&rd, c.cfg)
+			} else if mapper.Fetch != nil && !c.offline {
+				asset, err := mapper.Fetch(rd, c.cfg)
 				if errors.Cause(err) == converter.ErrEmptyIdentityField {
 					glog.Warningf("%s did not return a value for ID field. Skipping asset fetch.", key)
 					existingConverterAsset = nil
 				} else if err != nil {
-					return errors.Wrap(err, "fetching asset")
+					return names, errors.Wrap(err, "fetching asset")
 				} else {
 					existingConverterAsset = &asset
 				}
-				if existingConverterAsset != nil {
-					converted = mapper.MergeDelete(*existingConverterAsset, converted)
-					augmented, err := c.augmentAsset(&rd, c.cfg, converted)
-					if err != nil {
-						return errors.Wrap(err, "augmenting asset")
-					}
-					c.assets[key] = augmented
-				}
 			}
+			if existingConverterAsset == nil {
+				continue
+			}
+
+			deleted := converted
+			converted, err = c.merge(mapper.MergeDelete, *existingConverterAsset, converted)
+			if err != nil {
+				return names, errors.Wrap(err, "merging deleted asset")
+			}
+			augmented, err := c.augmentAsset(rd, c.cfg, converted)
+			if err != nil {
+				return names, errors.Wrap(err, "augmenting asset")
+			}
+			augmented, err = c.transform(augmented)
+			if err != nil {
+				return names, errors.Wrap(err, "transforming asset")
+			}
+			prov, err := c.newRemovalProvenance(plan, rc, deleted)
+			if err != nil {
+				return names, err
+			}
+			c.storeAsset(key, augmented, prov)
+			names = append(names, converted.Name)
 		}
 	}
 
-	return nil
+	return names, nil
 }
 
 // For create/update, we need to handle both the case of no merging,
 // and the case of merging. If merging, we expect both fetch and mergeCreateUpdate
 // to be present.
-func (c *Converter) addCreateOrUpdate(rc *tfjson.ResourceChange) error {
-	resource, _ := c.schema.ResourcesMap[rc.Type]
-	rd := NewFakeResourceData(
-		rc.Type,
-		resource.Schema,
-		rc.Change.After.(map[string]interface{}),
-	)
-
+// It returns the names of the assets the resource change was converted into.
+func (c *Converter) addCreateOrUpdate(plan string, rc *tfjson.ResourceChange, rd *FakeResourceData) ([]string, error) {
+	var names []string
 	for _, mapper := range c.mapperFuncs[rd.Kind()] {
-		convertedAssets, err := mapper.Convert(&rd, c.cfg)
+		convertedAssets, err := mapper.Convert(rd, c.cfg)
 		if err != nil {
 			if errors.Cause(err) == converter.ErrNoConversion {
 				continue
 			}
-			return errors.Wrap(err, "converting asset")
+			return names, errors.Wrap(err, "converting asset")
 		}
 
 		for _, converted := range convertedAssets {
 			key := converted.Type + converted.Name
+			contributed := converted
 
+			// A tombstone is replaced by the resource created again.
 			var existingConverterAsset *converter.Asset
-			if existing, exists := c.assets[key]; exists {
+			if existing, exists := c.assets[key]; exists && !existing.Deleted {
 				existingConverterAsset = &existing.converterAsset
 			} else if mapper.Fetch != nil && !c.offline {
-				asset, err := mapper.Fetch(&rd, c.cfg)
+				asset, err := mapper.Fetch(rd, c.cfg)
 				if errors.Cause(err) == converter.ErrEmptyIdentityField {
 					glog.Warningf("%s did not return a value for ID field. Skipping asset fetch.", key)
 					existingConverterAsset = nil
 				} else if err != nil {
-					return errors.Wrap(err, "fetching asset")
+					return names, errors.Wrap(err, "fetching asset")
 				} else {
 					existingConverterAsset = &asset
 				}
@@ -333,32 +590,82 @@ func (c *Converter) addCreateOrUpdate(rc *tfjson.ResourceChange) error {
 				if mapper.MergeCreateUpdate == nil {
 					// If a merge function does not exist ignore the asset and return
 					// a checkable error.
-					return fmt.Errorf("asset type %s: asset name %s %w", converted.Type, converted.Name, ErrDuplicateAsset)
+					return names, fmt.Errorf("asset type %s: asset name %s %w", converted.Type, converted.Name, ErrDuplicateAsset)
+				}
+				converted, err = c.merge(mapper.MergeCreateUpdate, *existingConverterAsset, converted)
+				if err != nil {
+					return names, errors.Wrap(err, "merging asset")
 				}
-				converted = mapper.MergeCreateUpdate(*existingConverterAsset, converted)
 			}
 
-			augmented, err := c.augmentAsset(&rd, c.cfg, converted)
+			augmented, err := c.augmentAsset(rd, c.cfg, converted)
 			if err != nil {
-				return errors.Wrap(err, "augmenting asset")
+				return names, errors.Wrap(err, "augmenting asset")
 			}
-			c.assets[key] = augmented
+			augmented, err = c.transform(augmented)
+			if err != nil {
+				return names, errors.Wrap(err, "transforming asset")
+			}
+			prov, err := c.newProvenance(plan, rc, contributed)
+			if err != nil {
+				return names, err
+			}
+			c.storeAsset(key, augmented, prov)
+			if final(mapper) {
+				c.final = append(c.final, key)
+			}
+			names = append(names, converted.Name)
 		}
 	}
 
-	return nil
+	return names, nil
+}
+
+// storeAsset stores a converted asset, keeping the plans and resource changes
+// that contributed to any asset it replaces.
+func (c *Converter) storeAsset(key string, asset Asset, prov Provenance) {
+	if existing, exists := c.assets[key]; exists {
+		asset.SourcePlans = existing.SourcePlans
+		asset.Provenance = existing.Provenance
+		// A resource merged into the asset without a location of its own
+		// (e.g. an IAM member) keeps the location of the asset. A
+		// tombstone replaced by the resource created again does not.
+		if asset.Resource != nil && existing.Resource != nil && (asset.Deleted || !existing.Deleted) {
+			asset.Resource.Location = mergeLocation(asset.Resource.Location, existing.Resource.Location)
+		}
+	}
+	if prov.SourcePlan != "" && !containsString(asset.SourcePlans, prov.SourcePlan) {
+		asset.SourcePlans = append(asset.SourcePlans, prov.SourcePlan)
+	}
+	asset.Provenance = append(asset.Provenance, prov)
+	c.assets[key] = asset
+	// A changed asset is streamed again or listed by Assets.
+	delete(c.streamed, key)
 }
 
 type byName []Asset
 
 func (s byName) Len() int           { return len(s) }
-func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byName) Less(i, j int) bool {
+	// The tombstone of a replaced resource follows its replacement.
+	if s[i].Name == s[j].Name {
+		return !s[i].Deleted && s[j].Deleted
+	}
+	return s[i].Name < s[j].Name
+}
 func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 
-// Assets lists all converted assets previously added by calls to AddResource.
+// Assets lists all converted assets previously added by calls to AddResource,
+// except the ones passed to the stream function (see SetStream).
 func (c *Converter) Assets() []Asset {
 	list := make([]Asset, 0, len(c.assets))
-	for _, a := range c.assets {
+	for key, a := range c.assets {
+		if c.streamed[key] {
+			continue
+		}
+		if prior, ok := c.priors[key]; ok {
+			a.Prior = &prior
+		}
 		list = append(list, a)
 	}
 	sort.Sort(byName(list))
@@ -375,31 +682,41 @@ func (c *Converter) augmentAsset(tfData converter.TerraformResourceData, cfg *co
 	if err != nil {
 		return Asset{}, fmt.Errorf("getting resource ancestry for project %v: %w", project, err)
 	}
+	// The update time is not part of the terraform resource data, it is the
+	// time of the conversion (see SetClock).
+	updateTime := c.updateTime()
+	timestamp := &Timestamp{
+		Seconds: int64(updateTime.Unix()),
+		Nanos:   int64(updateTime.UnixNano()),
+	}
+
 	var resource *AssetResource
 	if cai.Resource != nil {
 		resource = &AssetResource{
 			Version:              cai.Resource.Version,
 			DiscoveryDocumentURI: cai.Resource.DiscoveryDocumentURI,
 			DiscoveryName:        cai.Resource.DiscoveryName,
-			Parent:               fmt.Sprintf("//cloudresourcemanager.googleapis.com/projects/%v", project),
+			Parent:               assetParent(tfData, cai, project, ancestry),
 			Data:                 cai.Resource.Data,
+			Location:             assetLocation(tfData, cfg),
 		}
 	}
 
 	var policy *IAMPolicy
 	if cai.IAMPolicy != nil {
 		policy = &IAMPolicy{}
-		for _, b := range cai.IAMPolicy.Bindings {
-			policy.Bindings = append(policy.Bindings, IAMBinding{
-				Role:    b.Role,
-				Members: b.Members,
-			})
+		var bindings []converter.IAMBinding
+		bindings, policy.AuditConfigs = splitAuditConfigs(cai.IAMPolicy.Bindings)
+		policy.Bindings, err = splitConditionalBindings(bindings)
+		if err != nil {
+			return Asset{}, fmt.Errorf("getting IAM bindings of %v: %w", cai.Name, err)
 		}
 	}
 
 	var orgPolicy []*OrgPolicy
-	if cai.OrgPolicy != nil {
-		for _, o := range cai.OrgPolicy {
+	v1OrgPolicies, v2OrgPolicies := splitV2OrgPolicies(cai.OrgPolicy)
+	if v1OrgPolicies != nil {
+		for _, o := range v1OrgPolicies {
 			var listPolicy *ListPolicy
 			var booleanPolicy *BooleanPolicy
 			var restoreDefault *RestoreDefault
@@ -420,28 +737,34 @@ func (c *Converter) augmentAsset(tfData converter.TerraformResourceData, cfg *co
 			if o.RestoreDefault != nil {
 				restoreDefault = &RestoreDefault{}
 			}
-			//As time is not information in terraform resource data, time is fixed for testing purposes
-			fixedTime := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
 			orgPolicy = append(orgPolicy, &OrgPolicy{
 				Constraint:     o.Constraint,
 				ListPolicy:     listPolicy,
 				BooleanPolicy:  booleanPolicy,
 				RestoreDefault: restoreDefault,
-				UpdateTime: &Timestamp{
-					Seconds: int64(fixedTime.Unix()),
-					Nanos:   int64(fixedTime.UnixNano()),
-				},
+				UpdateTime:     timestamp,
 			})
 		}
 	}
 
-	return Asset{
+	asset := Asset{
 		Name:           cai.Name,
 		Type:           cai.Type,
 		Ancestry:       ancestry,
+		Ancestors:      assetAncestors(tfData, cai, ancestry),
 		Resource:       resource,
 		IAMPolicy:      policy,
 		OrgPolicy:      orgPolicy,
+		V2OrgPolicies:  v2OrgPolicies,
+		UpdateTime:     timestamp,
 		converterAsset: cai,
-	}, nil
+	}
+	ok, err := setAccessContextPolicy(&asset, cai)
+	if err != nil {
+		return Asset{}, err
+	}
+	if ok {
+		asset.Resource = nil
+	}
+	return asset, nil
 }
@@ -26,6 +26,7 @@ import (
 	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const testProject = "test-project"
@@ -46,6 +47,25 @@ func newTestConverter() (*Converter, error) {
 	return c, nil
 }
 
+// newTestResourceChange returns a change to the managed resource kind.name.
+// values are the planned values, or the prior ones if the resource is deleted.
+func newTestResourceChange(kind, name string, actions tfjson.Actions, values map[string]interface{}) *tfjson.ResourceChange {
+	rc := &tfjson.ResourceChange{
+		Address:      kind + "." + name,
+		Mode:         "managed",
+		Type:         kind,
+		Name:         name,
+		ProviderName: "google",
+		Change:       &tfjson.Change{Actions: actions},
+	}
+	if actions.Delete() {
+		rc.Change.Before = values
+	} else {
+		rc.Change.After = values
+	}
+	return rc
+}
+
 type configAttrGetter func(cfg *converter.Config) string
 
 func getCredentials(cfg *converter.Config) string {
@@ -163,6 +183,14 @@ func TestAddResourceChanges_unknownResourceIgnored(t *testing.T) {
 	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
 	assert.Nil(t, err)
 	assert.EqualValues(t, map[string]Asset{}, c.assets)
+	assert.Equal(t, []ResourceCoverage{{
+		Address:  rc.Address,
+		Type:     rc.Type,
+		Provider: "google",
+		Action:   "unknown",
+		Status:   CoverageUnsupported,
+		Reason:   "unknown to the google provider schema",
+	}}, c.Coverage())
 }
 
 func TestAddResourceChanges_unsupportedResourceIgnored(t *testing.T) {
@@ -188,6 +216,8 @@ func TestAddResourceChanges_unsupportedResourceIgnored(t *testing.T) {
 	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
 	assert.Nil(t, err)
 	assert.EqualValues(t, map[string]Asset{}, c.assets)
+	assert.Equal(t, CoverageUnsupported, c.Coverage()[0].Status)
+	assert.True(t, c.Coverage()[0].IsUncovered())
 }
 
 func TestAddResourceChanges_noopIgnored(t *testing.T) {
@@ -209,6 +239,8 @@ func TestAddResourceChanges_noopIgnored(t *testing.T) {
 	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
 	assert.Nil(t, err)
 	assert.EqualValues(t, map[string]Asset{}, c.assets)
+	assert.Equal(t, CoverageSkipped, c.Coverage()[0].Status)
+	assert.Equal(t, "no-op action", c.Coverage()[0].Reason)
 }
 
 func TestAddResourceChanges_deleteProcessed(t *testing.T) {
@@ -240,6 +272,7 @@ func TestAddResourceChanges_deleteProcessed(t *testing.T) {
 	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
 	assert.Nil(t, err)
 	assert.EqualValues(t, map[string]Asset{}, c.assets)
+	assert.Equal(t, CoverageSkipped, c.Coverage()[0].Status)
 }
 
 func TestAddResourceChanges_createOrUpdateOrDeleteCreateProcessed(t *testing.T) {
@@ -292,10 +325,171 @@ func TestAddResourceChanges_createOrUpdateOrDeleteCreateProcessed(t *testing.T)
 
 			caiKey := "compute.googleapis.com/Disk//compute.googleapis.com/projects/test-project/zones/us-central1-a/disks/test-disk"
 			assert.Contains(t, c.assets, caiKey)
+			assert.Equal(t, CoverageConverted, c.Coverage()[0].Status)
+			assert.Equal(t, []string{"//compute.googleapis.com/projects/test-project/zones/us-central1-a/disks/test-disk"}, c.Coverage()[0].AssetNames)
+		})
+	}
+}
+
+func TestAddResourceChanges_moves(t *testing.T) {
+	disk := map[string]interface{}{
+		"project":                   testProject,
+		"name":                      "test-disk",
+		"type":                      "pd-ssd",
+		"zone":                      "us-central1-a",
+		"image":                     "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
+		"physical_block_size_bytes": 4096,
+	}
+	before := make(map[string]interface{})
+	for k, v := range disk {
+		before[k] = v
+	}
+	before["type"] = "pd-standard"
+	cases := []struct {
+		name       string
+		actions    tfjson.Actions
+		wantAssets int
+	}{
+		{
+			name:       "MoveOnly",
+			actions:    tfjson.Actions{"no-op"},
+			wantAssets: 0,
+		},
+		{
+			name:       "MoveAndUpdate",
+			actions:    tfjson.Actions{"update"},
+			wantAssets: 1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			rc := tfjson.ResourceChange{
+				Address:      "module.disks.google_compute_disk.foo",
+				Mode:         "managed",
+				Type:         "google_compute_disk",
+				Name:         "foo",
+				ProviderName: "google",
+				Change: &tfjson.Change{
+					Actions: c.actions,
+					Before:  before,
+					After:   disk,
+				},
+			}
+			converter, err := newTestConverter()
+			assert.Nil(t, err)
+
+			converter.AddMoves(map[string]string{rc.Address: "google_compute_disk.foo"})
+			err = converter.AddResourceChanges([]*tfjson.ResourceChange{&rc})
+			assert.Nil(t, err)
+			assets := converter.Assets()
+			assert.Len(t, assets, c.wantAssets)
+			assert.Equal(t, "google_compute_disk.foo", converter.PreviousAddress("", rc.Address))
+			for _, a := range assets {
+				assert.Len(t, a.Provenance, 1)
+				assert.Equal(t, rc.Address, a.Provenance[0].Address)
+				assert.Equal(t, "google_compute_disk.foo", a.Provenance[0].PreviousAddress)
+				assert.Equal(t, "update", a.Provenance[0].Action)
+				// The asset before the move is converted from the values
+				// before the update.
+				require.NotNil(t, a.Prior)
+				assert.Equal(t, a.Name, a.Prior.Name)
+				assert.Contains(t, a.Resource.Data["type"], "pd-ssd")
+				assert.Contains(t, a.Prior.Resource.Data["type"], "pd-standard")
+			}
 		})
 	}
 }
 
+func TestAddPlanMoves_perPlan(t *testing.T) {
+	newDisk := func(name string) *tfjson.ResourceChange {
+		values := map[string]interface{}{
+			"project": testProject,
+			"name":    name,
+			"zone":    "us-central1-a",
+		}
+		return &tfjson.ResourceChange{
+			Address:      "google_compute_disk.foo",
+			Mode:         "managed",
+			Type:         "google_compute_disk",
+			Name:         "foo",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"update"},
+				Before:  values,
+				After:   values,
+			},
+		}
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	// Both plans have a resource at the same address, only the first one
+	// moves it there.
+	c.AddPlanMoves("a.json", map[string]string{"google_compute_disk.foo": "google_compute_disk.old"})
+	c.AddPlanMoves("b.json", nil)
+	require.NoError(t, c.AddPlanResourceChanges("a.json", []*tfjson.ResourceChange{newDisk("disk-a")}))
+	require.NoError(t, c.AddPlanResourceChanges("b.json", []*tfjson.ResourceChange{newDisk("disk-b")}))
+
+	assert.Equal(t, "google_compute_disk.old", c.PreviousAddress("a.json", "google_compute_disk.foo"))
+	assert.Empty(t, c.PreviousAddress("b.json", "google_compute_disk.foo"))
+	assets := c.Assets()
+	require.Len(t, assets, 2)
+	assert.Equal(t, "google_compute_disk.old", assets[0].Provenance[0].PreviousAddress)
+	assert.NotNil(t, assets[0].Prior)
+	assert.Empty(t, assets[1].Provenance[0].PreviousAddress)
+	assert.Nil(t, assets[1].Prior)
+}
+
+func TestAddPlanResourceChanges_mergesPlans(t *testing.T) {
+	newMember := func(address, member string) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{
+			Address:      address,
+			Mode:         "managed",
+			Type:         "google_project_iam_member",
+			Name:         "member",
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": testProject,
+					"role":    "roles/viewer",
+					"member":  member,
+				},
+			},
+		}
+	}
+	c, err := newTestConverter()
+	assert.Nil(t, err)
+
+	err = c.AddPlanResourceChanges("network.tfplan.json", []*tfjson.ResourceChange{newMember("google_project_iam_member.network", "user:network@example.com")})
+	assert.Nil(t, err)
+	err = c.AddPlanResourceChanges("app.tfplan.json", []*tfjson.ResourceChange{newMember("google_project_iam_member.app", "user:app@example.com")})
+	assert.Nil(t, err)
+
+	assets := c.Assets()
+	assert.Len(t, assets, 1)
+	assert.Equal(t, []string{"network.tfplan.json", "app.tfplan.json"}, assets[0].SourcePlans)
+	assert.Len(t, assets[0].IAMPolicy.Bindings, 1)
+	assert.ElementsMatch(t, []string{"user:network@example.com", "user:app@example.com"}, assets[0].IAMPolicy.Bindings[0].Members)
+	assert.Equal(t, []Provenance{
+		{
+			Address:    "google_project_iam_member.network",
+			Type:       "google_project_iam_member",
+			Provider:   "google",
+			Action:     "create",
+			SourcePlan: "network.tfplan.json",
+			Fields:     []string{`iam_policy.bindings["roles/viewer"].members["user:network@example.com"]`},
+		},
+		{
+			Address:    "google_project_iam_member.app",
+			Type:       "google_project_iam_member",
+			Provider:   "google",
+			Action:     "create",
+			SourcePlan: "app.tfplan.json",
+			Fields:     []string{`iam_policy.bindings["roles/viewer"].members["user:app@example.com"]`},
+		},
+	}, assets[0].Provenance)
+}
+
 func TestAddDuplicatedResources(t *testing.T) {
 	rcb1 := tfjson.ResourceChange{
 		Address:      "google_billing_budget.budget1",
@@ -505,3 +699,166 @@ func TestTimestampUnmarshalJSON(t *testing.T) {
 	}
 	assert.EqualValues(t, ts, expected)
 }
+
+func TestAddResourceChanges_orgPolicyUpdateTime(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	updateTime := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
+	c.SetClock(func() time.Time { return updateTime })
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{{
+		Address: "google_project_organization_policy.serial_port_policy",
+		Mode:    "managed",
+		Type:    "google_project_organization_policy",
+		Name:    "serial_port_policy",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"boolean_policy": []interface{}{map[string]interface{}{"enforced": true}},
+				"constraint":     "compute.disableSerialPortAccess",
+				"project":        testProject,
+			},
+		},
+	}})
+	require.NoError(t, err)
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	require.Len(t, assets[0].OrgPolicy, 1)
+	assert.Equal(t, &Timestamp{
+		Seconds: updateTime.Unix(),
+		Nanos:   updateTime.UnixNano(),
+	}, assets[0].OrgPolicy[0].UpdateTime)
+	assert.Equal(t, assets[0].OrgPolicy[0].UpdateTime, assets[0].UpdateTime)
+}
+
+func TestAddResourceChanges_sharedUpdateTime(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	start := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
+	calls := 0
+	c.SetClock(func() time.Time {
+		calls++
+		return start.Add(time.Duration(calls) * time.Second)
+	})
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_storage_bucket", "a", create, map[string]interface{}{
+			"name": "a", "location": "EU", "project": testProject,
+		}),
+		newTestResourceChange("google_storage_bucket", "b", create, map[string]interface{}{
+			"name": "b", "location": "EU", "project": testProject,
+		}),
+	})
+	require.NoError(t, err)
+	assets := c.Assets()
+	require.Len(t, assets, 2)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, assets[0].UpdateTime, assets[1].UpdateTime)
+}
+
+func TestAddResourceChanges_nonGoogleResource(t *testing.T) {
+	rc := tfjson.ResourceChange{
+		Address:      "random_id.foo",
+		Mode:         "managed",
+		Type:         "random_id",
+		Name:         "foo",
+		ProviderName: "registry.terraform.io/hashicorp/random",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+		},
+	}
+	c, err := newTestConverter()
+	assert.Nil(t, err)
+
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{&rc})
+	assert.Nil(t, err)
+	assert.Equal(t, CoverageNonGoogle, c.Coverage()[0].Status)
+	assert.False(t, c.Coverage()[0].IsUncovered())
+}
+
+func TestAddResourceChanges_continueOnError(t *testing.T) {
+	newDisk := func(name, project string) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{
+			Address:      "google_compute_disk." + name,
+			Mode:         "managed",
+			Type:         "google_compute_disk",
+			Name:         name,
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project": project,
+					"name":    name,
+					"zone":    "us-central1-a",
+				},
+			},
+		}
+	}
+	// The ancestry of other projects cannot be found offline.
+	changes := []*tfjson.ResourceChange{newDisk("bad", "other-project"), newDisk("good", testProject)}
+
+	c, err := newTestConverter()
+	assert.Nil(t, err)
+	err = c.AddResourceChanges(changes)
+	assert.NotNil(t, err)
+
+	c, err = newTestConverter()
+	assert.Nil(t, err)
+	c.SetContinueOnError(true)
+	err = c.AddPlanResourceChanges("plan.json", changes)
+	assert.Nil(t, err)
+	assert.Len(t, c.Assets(), 1)
+	if assert.Len(t, c.Errors(), 1) {
+		rerr := c.Errors()[0]
+		assert.Equal(t, "google_compute_disk.bad", rerr.Address)
+		assert.Equal(t, "google_compute_disk", rerr.Type)
+		assert.Equal(t, "plan.json", rerr.SourcePlan)
+		assert.Contains(t, rerr.Error(), "cannot fetch ancestry in offline mode")
+		chain := rerr.Chain()
+		assert.Equal(t, rerr.Err.Error(), chain[0])
+		assert.Contains(t, chain[len(chain)-1], "cannot fetch ancestry in offline mode")
+	}
+	assert.Equal(t, CoverageError, c.Coverage()[0].Status)
+	assert.Equal(t, CoverageConverted, c.Coverage()[1].Status)
+}
+
+func TestAddResourceChanges_resourceDataDiagnostics(t *testing.T) {
+	newDisk := func(name string, labels interface{}) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{
+			Address:      "google_compute_disk." + name,
+			Mode:         "managed",
+			Type:         "google_compute_disk",
+			Name:         name,
+			ProviderName: "google",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After: map[string]interface{}{
+					"project":       testProject,
+					"name":          name,
+					"zone":          "us-central1-a",
+					"labels":        labels,
+					"new_attribute": "from a newer provider",
+				},
+			},
+		}
+	}
+	changes := []*tfjson.ResourceChange{
+		newDisk("good", map[string]interface{}{"env": "dev"}),
+		newDisk("bad", map[string]interface{}{"env": []int{1}}),
+	}
+
+	c, err := newTestConverter()
+	assert.Nil(t, err)
+	c.SetContinueOnError(true)
+	err = c.AddResourceChanges(changes)
+	assert.Nil(t, err)
+
+	coverage := c.Coverage()
+	assert.Equal(t, CoverageConverted, coverage[0].Status)
+	assert.Equal(t, []string{"new_attribute"}, coverage[0].DroppedAttributes)
+	assert.Equal(t, CoverageError, coverage[1].Status)
+	assert.Contains(t, coverage[1].Reason, `attribute "labels.env"`)
+	if assert.Len(t, c.Errors(), 1) {
+		var attrErr *AttributeError
+		assert.True(t, errors.As(c.Errors()[0], &attrErr))
+	}
+}
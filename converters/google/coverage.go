@@ -0,0 +1,97 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
+)
+
+// CoverageStatus is the outcome of converting a resource change.
+type CoverageStatus string
+
+const (
+	// CoverageConverted means the resource change was converted into assets.
+	CoverageConverted CoverageStatus = "converted"
+	// CoverageUnsupported means the resource type belongs to the google
+	// provider, but cannot be converted.
+	CoverageUnsupported CoverageStatus = "unsupported"
+	// CoverageNonGoogle means the resource does not belong to the google
+	// provider.
+	CoverageNonGoogle CoverageStatus = "non_google"
+	// CoverageSkipped means the resource change was not converted because
+	// of its action, e.g. no-op or read.
+	CoverageSkipped CoverageStatus = "skipped"
+	// CoverageError means converting the resource change failed.
+	CoverageError CoverageStatus = "error"
+)
+
+// ResourceCoverage records the outcome of converting one resource change.
+type ResourceCoverage struct {
+	Address  string         `json:"address"`
+	Type     string         `json:"type"`
+	Provider string         `json:"provider"`
+	Action   string         `json:"action"`
+	Status   CoverageStatus `json:"status"`
+	// Reason explains why the resource change was not converted.
+	Reason string `json:"reason,omitempty"`
+	// AssetNames lists the assets the resource change was converted into.
+	AssetNames []string `json:"asset_names,omitempty"`
+	// SourcePlan is the plan the resource change was read from, if known.
+	SourcePlan string `json:"source_plan,omitempty"`
+	// DroppedAttributes lists the attributes of the resource that are not
+	// in the provider schema known to the converter and were not converted.
+	DroppedAttributes []string `json:"dropped_attributes,omitempty"`
+}
+
+// IsGoogle reports whether the resource is a resource of the google
+// provider, whether or not it is known to the converter.
+func (r ResourceCoverage) IsGoogle() bool {
+	return strings.HasPrefix(r.Type, "google_")
+}
+
+// IsUncovered reports whether the resource is a google resource that
+// could not be converted.
+func (r ResourceCoverage) IsUncovered() bool {
+	return r.IsGoogle() && (r.Status == CoverageUnsupported || r.Status == CoverageError)
+}
+
+// Coverage lists the outcome of every resource change added to the
+// converter, in the order they were processed.
+func (c *Converter) Coverage() []ResourceCoverage {
+	return c.coverage
+}
+
+// recordCoverage records the outcome of a resource change. rd is the resource
+// data read from the resource change, if any.
+func (c *Converter) recordCoverage(plan string, rc *tfjson.ResourceChange, rd *FakeResourceData, status CoverageStatus, reason string, names []string) {
+	var dropped []string
+	if rd != nil {
+		dropped = rd.DroppedAttributes()
+	}
+	c.coverage = append(c.coverage, ResourceCoverage{
+		Address:           rc.Address,
+		Type:              rc.Type,
+		Provider:          rc.ProviderName,
+		Action:            tfplan.ActionOf(rc).String(),
+		Status:            status,
+		Reason:            reason,
+		AssetNames:        names,
+		SourcePlan:        plan,
+		DroppedAttributes: dropped,
+	})
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
+)
+
+// SetDeletions sets whether resources deleted by the plan are converted into
+// tombstones: assets with Deleted set, converted from the values of the
+// resources before the change. Resources replaced by the plan (in either
+// order) get a tombstone next to the asset of their replacement. It must be
+// called before the resource changes are added.
+func (c *Converter) SetDeletions(deletions bool) {
+	c.deletions = deletions
+}
+
+// replacedKeySuffix is appended to the asset keys of the tombstones of
+// replaced resources.
+const replacedKeySuffix = " (replaced)"
+
+// addTombstones converts a deleted resource into tombstones. Only the mappers
+// that convert a whole resource into an asset of its own produce tombstones:
+// deleting a resource that is merged into other assets (e.g. an IAM member
+// or an org policy) does not delete those assets.
+// The tombstone of a replaced resource is stored apart from the asset of its
+// replacement, which usually has the same name.
+// It returns the names of the tombstones.
+func (c *Converter) addTombstones(plan string, rc *tfjson.ResourceChange, rd *FakeResourceData) ([]string, error) {
+	replaced := tfplan.ActionOf(rc).IsReplace()
+	var names []string
+	for _, mapper := range c.mapperFuncs[rd.Kind()] {
+		if mapper.MergeDelete != nil {
+			continue
+		}
+		convertedItems, err := mapper.Convert(rd, c.cfg)
+		if err != nil {
+			if errors.Cause(err) == converter.ErrNoConversion {
+				continue
+			}
+			return names, errors.Wrap(err, "converting asset")
+		}
+
+		for _, converted := range convertedItems {
+			if converted.Resource == nil {
+				continue
+			}
+			augmented, err := c.augmentAsset(rd, c.cfg, converted)
+			if err != nil {
+				return names, errors.Wrap(err, "augmenting asset")
+			}
+			augmented.Deleted = true
+			augmented, err = c.transform(augmented)
+			if err != nil {
+				return names, errors.Wrap(err, "transforming asset")
+			}
+			key := converted.Type + converted.Name
+			if replaced {
+				key += replacedKeySuffix
+			}
+			prov, err := c.newProvenance(plan, rc, converted)
+			if err != nil {
+				return names, err
+			}
+			c.storeAsset(key, augmented, prov)
+			names = append(names, converted.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddResourceChanges_deletions(t *testing.T) {
+	changes := []*tfjson.ResourceChange{
+		newTestResourceChange("google_storage_bucket", "b", tfjson.Actions{"delete"}, map[string]interface{}{
+			"name": "b", "location": "EU", "project": testProject,
+		}),
+		// Deleting a resource merged into other assets deletes no asset.
+		newTestResourceChange("google_project_iam_member", "m", tfjson.Actions{"delete"}, map[string]interface{}{
+			"project": testProject, "role": "roles/viewer", "member": "user:jane@example.com",
+		}),
+	}
+
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddResourceChanges(changes))
+	assert.Empty(t, c.Assets())
+
+	c, err = newTestConverter()
+	require.NoError(t, err)
+	c.SetDeletions(true)
+	require.NoError(t, c.AddResourceChanges(changes))
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//storage.googleapis.com/b", assets[0].Name)
+	assert.True(t, assets[0].Deleted)
+	require.NotNil(t, assets[0].Resource)
+	assert.Equal(t, "b", assets[0].Resource.Data["name"])
+	assert.Equal(t, "eu", assets[0].Resource.Location)
+	require.Len(t, assets[0].Provenance, 1)
+	assert.Equal(t, "google_storage_bucket.b", assets[0].Provenance[0].Address)
+
+	coverage := c.Coverage()
+	require.Len(t, coverage, 2)
+	assert.Equal(t, CoverageConverted, coverage[0].Status)
+	assert.Equal(t, []string{"//storage.googleapis.com/b"}, coverage[0].AssetNames)
+	assert.Equal(t, CoverageSkipped, coverage[1].Status)
+}
+
+func TestAddResourceChanges_replacements(t *testing.T) {
+	for _, actions := range []tfjson.Actions{{"delete", "create"}, {"create", "delete"}} {
+		t.Run(string(actions[0])+"-"+string(actions[1]), func(t *testing.T) {
+			rc := newTestResourceChange("google_storage_bucket", "b", actions, nil)
+			rc.Change.Before = map[string]interface{}{"name": "b", "location": "EU", "project": testProject}
+			rc.Change.After = map[string]interface{}{"name": "b", "location": "US", "project": testProject}
+
+			c, err := newTestConverter()
+			require.NoError(t, err)
+			require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+			assets := c.Assets()
+			require.Len(t, assets, 1)
+			assert.False(t, assets[0].Deleted)
+			assert.Equal(t, "us", assets[0].Resource.Location)
+
+			c, err = newTestConverter()
+			require.NoError(t, err)
+			c.SetDeletions(true)
+			require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+			assets = c.Assets()
+			require.Len(t, assets, 2)
+			locations := make(map[bool]string)
+			for _, a := range assets {
+				assert.Equal(t, "//storage.googleapis.com/b", a.Name)
+				locations[a.Deleted] = a.Resource.Location
+			}
+			assert.Equal(t, map[bool]string{false: "us", true: "eu"}, locations)
+
+			coverage := c.Coverage()
+			require.Len(t, coverage, 1)
+			assert.Equal(t, CoverageConverted, coverage[0].Status)
+			assert.Equal(t, []string{"//storage.googleapis.com/b", "//storage.googleapis.com/b"}, coverage[0].AssetNames)
+		})
+	}
+}
+
+func TestAddResourceChanges_deletionRecreated(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	c.SetDeletions(true)
+	// A bucket deleted at one address and created again at another one.
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		{
+			Address: "google_storage_bucket.new",
+			Type:    "google_storage_bucket",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After:   map[string]interface{}{"name": "b", "location": "US", "project": testProject},
+			},
+		},
+		newTestResourceChange("google_storage_bucket", "old", tfjson.Actions{"delete"}, map[string]interface{}{
+			"name": "b", "location": "EU", "project": testProject,
+		}),
+		newTestResourceChange("google_storage_bucket_iam_member", "m", tfjson.Actions{"delete"}, map[string]interface{}{
+			"bucket": "b", "role": "roles/storage.admin", "member": "user:jane@example.com",
+		}),
+	})
+	require.NoError(t, err)
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.False(t, assets[0].Deleted)
+	assert.Equal(t, "us", assets[0].Resource.Location)
+	assert.Len(t, assets[0].Provenance, 2)
+}
+
+func TestNewRemovalProvenance(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	rc := newTestResourceChange("google_project_iam_member", "m", tfjson.Actions{"delete"}, nil)
+	prov, err := c.newRemovalProvenance("", rc, converter.Asset{
+		IAMPolicy: &converter.IAMPolicy{Bindings: []converter.IAMBinding{
+			{Role: "roles/viewer", Members: []string{"user:jane@example.com"}},
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "delete", prov.Action)
+	assert.Empty(t, prov.Fields)
+	assert.Equal(t, []string{`iam_policy.bindings["roles/viewer"].members["user:jane@example.com"]`}, prov.Removed)
+}
@@ -19,6 +19,8 @@ package google
 
 import (
 	"fmt"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +33,21 @@ type FakeResourceData struct {
 	reader schema.FieldReader
 	kind   string
 	schema map[string]*schema.Schema
+	// prior reads the values of the resource before the change, if the
+	// resource exists in the prior state.
+	prior schema.FieldReader
+	id    string
+	// timeouts holds the "timeouts" block of the resource, if any.
+	timeouts map[string]interface{}
+	// dropped lists the attributes of the values that are not in the schema.
+	dropped []string
+	// unset holds the attributes that are null in the values and have no
+	// default value.
+	unset map[string]bool
+	// before and after are the raw values of the resource change the data
+	// was read from, if any, e.g. for plugins.
+	before map[string]interface{}
+	after  map[string]interface{}
 }
 
 // Kind returns the type of resource (i.e. "google_storage_bucket").
@@ -40,7 +57,12 @@ func (d *FakeResourceData) Kind() string {
 
 // Id returns the ID of the resource from state.
 func (d *FakeResourceData) Id() string {
-	return ""
+	return d.id
+}
+
+// SetId sets the ID of the resource.
+func (d *FakeResourceData) SetId(id string) {
+	d.id = id
 }
 
 // Get reads a single field by key.
@@ -52,7 +74,11 @@ func (d *FakeResourceData) Get(name string) interface{} {
 // Get reads a single field by key and returns a boolean indicating
 // whether the field exists.
 func (d *FakeResourceData) GetOk(name string) (interface{}, bool) {
-	res, err := d.reader.ReadField(strings.Split(name, "."))
+	return d.getOk(d.reader, name)
+}
+
+func (d *FakeResourceData) getOk(reader schema.FieldReader, name string) (interface{}, bool) {
+	res, err := reader.ReadField(strings.Split(name, "."))
 	if err != nil {
 		return nil, false
 	}
@@ -66,33 +92,134 @@ func (d *FakeResourceData) GetOk(name string) (interface{}, bool) {
 	return res.ValueOrZero(schemaPath[len(schemaPath)-1]), res.Exists && !res.Computed
 }
 
-// GetOkExists currently just calls GetOk but should be updated to support
-// schema.ResourceData.GetOkExists functionality.
+// GetOkExists reads a single field by key and returns a boolean indicating
+// whether the field is set, even to its zero value (e.g. a boolean set
+// to false). Unlike GetOk, it returns false for fields that are null in
+// the plan and have no default value.
 func (d *FakeResourceData) GetOkExists(name string) (interface{}, bool) {
-	return d.GetOk(name)
+	val, ok := d.GetOk(name)
+	return val, ok && !d.unset[name]
+}
+
+// GetChange returns the values of a field before and after the change. The
+// value before the change is the zero value for new resources.
+func (d *FakeResourceData) GetChange(name string) (interface{}, interface{}) {
+	n := d.Get(name)
+	if d.prior == nil {
+		schemaPath := addrToSchema(strings.Split(name, "."), d.schema)
+		if len(schemaPath) == 0 {
+			return nil, n
+		}
+		return schemaPath[len(schemaPath)-1].ZeroValue(), n
+	}
+	o, _ := d.getOk(d.prior, name)
+	return o, n
+}
+
+// HasChange reports whether the value of a field changes.
+func (d *FakeResourceData) HasChange(name string) bool {
+	o, n := d.GetChange(name)
+	// Sets cannot be compared with reflect.DeepEqual as they hold their hash function.
+	if eq, ok := o.(interface{ Equal(interface{}) bool }); ok {
+		return !eq.Equal(n)
+	}
+	return !reflect.DeepEqual(o, n)
+}
+
+// Timeout returns the timeout of the given operation (e.g.
+// schema.TimeoutCreate) configured in the "timeouts" block of the resource,
+// or the default timeout of 20 minutes.
+func (d *FakeResourceData) Timeout(key string) time.Duration {
+	if v, ok := d.timeouts[key].(string); ok {
+		if timeout, err := time.ParseDuration(v); err == nil {
+			return timeout
+		}
+	}
+	return 20 * time.Minute
 }
 
 // These methods are required by some mappers but we don't actually have (or need)
 // implementations for them.
-func (d *FakeResourceData) HasChange(string) bool             { return false }
 func (d *FakeResourceData) Set(string, interface{}) error     { return nil }
-func (d *FakeResourceData) SetId(string)                      {}
 func (d *FakeResourceData) GetProviderMeta(interface{}) error { return nil }
-func (d *FakeResourceData) Timeout(key string) time.Duration  { return time.Duration(1) }
-
-func NewFakeResourceData(kind string, resourceSchema map[string]*schema.Schema, values map[string]interface{}) FakeResourceData {
-	state := map[string]string{}
-	var address []string
-	attributes(values, address, state, resourceSchema)
-	reader := &schema.MapFieldReader{
-		Map:    schema.BasicMapReader(state),
-		Schema: resourceSchema,
+
+// DroppedAttributes lists the attributes of the resource values that are
+// not in the resource schema and therefore cannot be read, e.g. attributes
+// added by a newer provider version.
+func (d *FakeResourceData) DroppedAttributes() []string {
+	return d.dropped
+}
+
+// AttributeError is an error reading a single attribute of resource values.
+type AttributeError struct {
+	// Path is the address of the attribute, e.g. "labels.env" or "rule.0.ports".
+	Path string
+	Err  error
+}
+
+func (e *AttributeError) Error() string {
+	return fmt.Sprintf("attribute %q: %v", e.Path, e.Err)
+}
+
+func (e *AttributeError) Unwrap() error {
+	return e.Err
+}
+
+// NewFakeResourceData creates resource data from the values of a resource
+// in a plan. It returns an *AttributeError if a value does not fit the schema.
+func NewFakeResourceData(kind string, resourceSchema map[string]*schema.Schema, values map[string]interface{}) (FakeResourceData, error) {
+	return NewFakeResourceDataWithPrior(kind, resourceSchema, nil, values)
+}
+
+// NewFakeResourceDataWithPrior creates resource data from the values of a
+// resource before (prior, nil for new resources) and after a change (values),
+// e.g. the "before" and "after" values of a resource change in a plan.
+// HasChange and GetChange compare them, and Id is read from the prior values.
+func NewFakeResourceDataWithPrior(kind string, resourceSchema map[string]*schema.Schema, prior, values map[string]interface{}) (FakeResourceData, error) {
+	root := &schema.Schema{Type: typeObject, Elem: resourceSchema}
+	// Read all values once to find errors, dropped and unset attributes
+	// up front rather than when a mapper reads them.
+	w := &valueWalk{unset: map[string]bool{}}
+	if _, err := readValue(values, root, nil, w); err != nil {
+		return FakeResourceData{}, err
 	}
-	return FakeResourceData{
+	sort.Strings(w.dropped)
+	d := FakeResourceData{
 		kind:   kind,
 		schema: resourceSchema,
-		reader: reader,
+		reader: &jsonFieldReader{
+			values: values,
+			schema: resourceSchema,
+		},
+		dropped: w.dropped,
+		unset:   w.unset,
+	}
+	d.timeouts, _ = values["timeouts"].(map[string]interface{})
+	d.id, _ = values["id"].(string)
+
+	if prior != nil {
+		if _, err := readValue(prior, root, nil, nil); err != nil {
+			return FakeResourceData{}, fmt.Errorf("reading prior values: %w", err)
+		}
+		d.prior = &jsonFieldReader{
+			values: prior,
+			schema: resourceSchema,
+		}
+		if id, ok := prior["id"].(string); ok && id != "" {
+			d.id = id
+		}
 	}
+	return d, nil
+}
+
+// droppedAttributes lists the attributes of the values that are not in the
+// resource schema or do not match it, see DroppedAttributes.
+func droppedAttributes(values map[string]interface{}, resourceSchema map[string]*schema.Schema) []string {
+	w := &valueWalk{unset: map[string]bool{}}
+	// Errors stop the walk; the attributes found up to then are still dropped.
+	readValue(values, &schema.Schema{Type: typeObject, Elem: resourceSchema}, nil, w)
+	sort.Strings(w.dropped)
+	return w.dropped
 }
 
 // addrToSchema finds the final element schema for the given address
@@ -101,8 +228,6 @@ func NewFakeResourceData(kind string, resourceSchema map[string]*schema.Schema,
 // NOTE: This function was copied from the terraform library:
 // github.com/hashicorp/terraform/helper/schema/field_reader.go
 func addrToSchema(addr []string, schemaMap map[string]*schema.Schema) []*schema.Schema {
-	const typeObject = 999
-
 	current := &schema.Schema{
 		Type: typeObject,
 		Elem: schemaMap,
@@ -221,85 +346,3 @@ func addrToSchema(addr []string, schemaMap map[string]*schema.Schema) []*schema.
 
 	return result
 }
-
-// attributes function takes json parsed JSON object (value param) and fill map[string]string with it's
-// content (state param) for example JSON:
-//
-//	{
-//		"foo": {
-//			"name" : "value"
-//		},
-//	  "list": ["item1", "item2"]
-//	}
-//
-// will be translated to map with following key/value set:
-//
-//	foo.name => "value"
-//	list.# => 2
-//	list.0 => "item1"
-//	list.1 => "item2"
-//
-// Map above will be passed to schema.BasicMapReader that have all appropriate logic to read fields
-// correctly during conversion to CAI.
-func attributes(value interface{}, address []string, state map[string]string, schemas map[string]*schema.Schema) {
-	schemaArr := addrToSchema(address, schemas)
-	if len(schemaArr) == 0 {
-		return
-	}
-	sch := schemaArr[len(schemaArr)-1]
-	addr := strings.Join(address, ".")
-	// int is special case, can't use handle it in main switch because number will be always parsed from JSON as float
-	// need to identify it by schema.TypeInt and convert to int from int or float
-	if sch.Type == schema.TypeInt && value != nil {
-		switch value.(type) {
-		case int:
-			state[addr] = strconv.Itoa(value.(int))
-		case float64:
-			state[addr] = strconv.Itoa(int(value.(float64)))
-		case float32:
-			state[addr] = strconv.Itoa(int(value.(float32)))
-		}
-		return
-	}
-
-	switch value.(type) {
-	case nil:
-		defaultValue, err := sch.DefaultValue()
-		if err != nil {
-			panic(fmt.Sprintf("error getting default value for %v", address))
-		}
-		if defaultValue == nil {
-			defaultValue = sch.ZeroValue()
-		}
-		attributes(defaultValue, address, state, schemas)
-	case float64:
-		state[addr] = strconv.FormatFloat(value.(float64), 'f', 6, 64)
-	case float32:
-		state[addr] = strconv.FormatFloat(value.(float64), 'f', 6, 32)
-	case string:
-		state[addr] = value.(string)
-	case bool:
-		state[addr] = strconv.FormatBool(value.(bool))
-	case int:
-		state[addr] = strconv.Itoa(value.(int))
-	case []interface{}:
-		arr := value.([]interface{})
-		countAddr := addr + ".#"
-		state[countAddr] = strconv.Itoa(len(arr))
-		for i, e := range arr {
-			addr := append(address, strconv.Itoa(i))
-			attributes(e, addr, state, schemas)
-		}
-	case map[string]interface{}:
-		m := value.(map[string]interface{})
-		for k, v := range m {
-			addr := append(address, k)
-			attributes(v, addr, state, schemas)
-		}
-	case *schema.Set:
-		set := value.(*schema.Set)
-		attributes(set.List(), address, state, schemas)
-	default:
-		panic(fmt.Sprintf("unrecognized type %T", value))
-	}
-}
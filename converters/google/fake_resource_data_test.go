@@ -14,8 +14,11 @@
 package google
 
 import (
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	provider "github.com/hashicorp/terraform-provider-google/v3/google"
 	"github.com/stretchr/testify/assert"
 )
@@ -30,11 +33,12 @@ func TestFakeResourceData_kind(t *testing.T) {
 		"image": "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
 		"physical_block_size_bytes": 4096,
 	}
-	d := NewFakeResourceData(
+	d, err := NewFakeResourceData(
 		"google_compute_disk",
 		p.ResourcesMap["google_compute_disk"].Schema,
 		values,
 	)
+	assert.Nil(t, err)
 	assert.Equal(t, d.Kind(), "google_compute_disk")
 }
 
@@ -49,11 +53,12 @@ func TestFakeResourceData_id(t *testing.T) {
 		"image": "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
 		"physical_block_size_bytes": 4096,
 	}
-	d := NewFakeResourceData(
+	d, err := NewFakeResourceData(
 		"google_compute_disk",
 		p.ResourcesMap["google_compute_disk"].Schema,
 		values,
 	)
+	assert.Nil(t, err)
 	assert.Equal(t, d.Id(), "")
 }
 
@@ -68,11 +73,12 @@ func TestFakeResourceData_get(t *testing.T) {
 		"image": "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
 		"physical_block_size_bytes": 4096,
 	}
-	d := NewFakeResourceData(
+	d, err := NewFakeResourceData(
 		"google_compute_disk",
 		p.ResourcesMap["google_compute_disk"].Schema,
 		values,
 	)
+	assert.Nil(t, err)
 	assert.Equal(t, d.Get("name"), "test-disk")
 }
 
@@ -87,11 +93,12 @@ func TestFakeResourceData_getOkOk(t *testing.T) {
 		"image": "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
 		"physical_block_size_bytes": 4096,
 	}
-	d := NewFakeResourceData(
+	d, err := NewFakeResourceData(
 		"google_compute_disk",
 		p.ResourcesMap["google_compute_disk"].Schema,
 		values,
 	)
+	assert.Nil(t, err)
 	res, ok := d.GetOk("name")
 	assert.Equal(t, res, "test-disk")
 	assert.True(t, ok)
@@ -108,12 +115,146 @@ func TestFakeResourceData_getOkNotOk(t *testing.T) {
 		"image": "projects/debian-cloud/global/images/debian-8-jessie-v20170523",
 		"physical_block_size_bytes": 4096,
 	}
-	d := NewFakeResourceData(
+	d, err := NewFakeResourceData(
 		"google_compute_disk",
 		p.ResourcesMap["google_compute_disk"].Schema,
 		values,
 	)
+	assert.Nil(t, err)
 	res, ok := d.GetOk("incorrect")
 	assert.Nil(t, res)
 	assert.False(t, ok)
 }
+
+func TestFakeResourceData_droppedAttributes(t *testing.T) {
+	p := provider.Provider()
+
+	values := map[string]interface{}{
+		"name":          "test-disk",
+		"zone":          "us-central1-a",
+		"new_attribute": "from a newer provider",
+		"disk_encryption_key": []interface{}{
+			map[string]interface{}{"new_nested": true},
+		},
+	}
+	d, err := NewFakeResourceData(
+		"google_compute_disk",
+		p.ResourcesMap["google_compute_disk"].Schema,
+		values,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"disk_encryption_key.0.new_nested", "new_attribute"}, d.DroppedAttributes())
+	assert.Equal(t, "test-disk", d.Get("name"))
+}
+
+func TestFakeResourceData_invalidValue(t *testing.T) {
+	p := provider.Provider()
+
+	cases := []struct {
+		name   string
+		values map[string]interface{}
+		path   string
+	}{
+		{
+			name:   "UnrecognizedType",
+			values: map[string]interface{}{"labels": map[string]interface{}{"env": struct{}{}}},
+			path:   "labels.env",
+		},
+		{
+			name:   "NotANumber",
+			values: map[string]interface{}{"size": "ten"},
+			path:   "size",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := NewFakeResourceData(
+				"google_compute_disk",
+				p.ResourcesMap["google_compute_disk"].Schema,
+				c.values,
+			)
+			var attrErr *AttributeError
+			if assert.True(t, errors.As(err, &attrErr)) {
+				assert.Equal(t, c.path, attrErr.Path)
+			}
+		})
+	}
+}
+
+func TestFakeResourceData_withPrior(t *testing.T) {
+	p := provider.Provider()
+
+	prior := map[string]interface{}{
+		"id":     "projects/test-project/zones/us-central1-a/disks/test-disk",
+		"name":   "test-disk",
+		"zone":   "us-central1-a",
+		"size":   10,
+		"labels": map[string]interface{}{"env": "dev"},
+	}
+	values := map[string]interface{}{
+		"name":   "test-disk",
+		"zone":   "us-central1-a",
+		"size":   20,
+		"labels": map[string]interface{}{"env": "dev"},
+		"timeouts": map[string]interface{}{
+			"update": "5m",
+		},
+	}
+	d, err := NewFakeResourceDataWithPrior(
+		"google_compute_disk",
+		p.ResourcesMap["google_compute_disk"].Schema,
+		prior,
+		values,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "projects/test-project/zones/us-central1-a/disks/test-disk", d.Id())
+	assert.True(t, d.HasChange("size"))
+	assert.False(t, d.HasChange("labels"))
+	assert.False(t, d.HasChange("name"))
+	o, n := d.GetChange("size")
+	assert.Equal(t, 10, o)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, 5*time.Minute, d.Timeout(schema.TimeoutUpdate))
+	assert.Equal(t, 20*time.Minute, d.Timeout(schema.TimeoutCreate))
+	assert.Empty(t, d.DroppedAttributes())
+}
+
+func TestFakeResourceData_hasChangeCreate(t *testing.T) {
+	p := provider.Provider()
+
+	values := map[string]interface{}{
+		"name": "test-disk",
+		"zone": "us-central1-a",
+	}
+	d, err := NewFakeResourceData(
+		"google_compute_disk",
+		p.ResourcesMap["google_compute_disk"].Schema,
+		values,
+	)
+	assert.Nil(t, err)
+	assert.Equal(t, "", d.Id())
+	assert.True(t, d.HasChange("name"))
+	assert.False(t, d.HasChange("description"))
+}
+
+func TestFakeResourceData_getOkExists(t *testing.T) {
+	p := provider.Provider()
+
+	values := map[string]interface{}{
+		"name":                    "test-network",
+		"auto_create_subnetworks": false,
+		"description":             nil,
+	}
+	d, err := NewFakeResourceData(
+		"google_compute_network",
+		p.ResourcesMap["google_compute_network"].Schema,
+		values,
+	)
+	assert.Nil(t, err)
+	res, ok := d.GetOkExists("auto_create_subnetworks")
+	assert.Equal(t, false, res)
+	assert.True(t, ok)
+	res, ok = d.GetOkExists("description")
+	assert.Equal(t, "", res)
+	assert.False(t, ok)
+}
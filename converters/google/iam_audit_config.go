@@ -0,0 +1,326 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// AuditConfig enables the audit logs of a service ("allServices" for all
+// services).
+type AuditConfig struct {
+	Service         string           `json:"service"`
+	AuditLogConfigs []AuditLogConfig `json:"audit_log_configs"`
+}
+
+// AuditLogConfig enables a type of audit logs (e.g. "DATA_READ") for all
+// members but the exempted ones.
+type AuditLogConfig struct {
+	LogType         string   `json:"log_type"`
+	ExemptedMembers []string `json:"exempted_members,omitempty"`
+}
+
+// The conversion library has no audit configs in its IAM policies, so the
+// audit log configs of an asset are kept in the bindings of its
+// converter.IAMPolicy: auditLogConfigRole(service, logType) is bound to
+// auditLogConfigMember and the exempted members. The merge functions of the
+// library keep these bindings as they never match the roles or members of
+// other IAM resources, and augmentAsset turns them back into audit configs.
+const (
+	auditLogConfigRolePrefix = "auditConfigs/"
+	auditLogConfigMember     = "auditLogConfig:enabled"
+)
+
+func auditLogConfigRole(service, logType string) string {
+	return auditLogConfigRolePrefix + service + "/" + logType
+}
+
+// splitAuditLogConfigRole returns the service and log type of a role
+// returned by auditLogConfigRole.
+func splitAuditLogConfigRole(role string) (service, logType string, ok bool) {
+	if !strings.HasPrefix(role, auditLogConfigRolePrefix) {
+		return "", "", false
+	}
+	role = strings.TrimPrefix(role, auditLogConfigRolePrefix)
+	i := strings.LastIndex(role, "/")
+	if i < 0 {
+		return "", "", false
+	}
+	return role[:i], role[i+1:], true
+}
+
+// auditConfigBindings returns the bindings keeping the given audit configs.
+func auditConfigBindings(configs []AuditConfig) []converter.IAMBinding {
+	var bindings []converter.IAMBinding
+	for _, ac := range configs {
+		for _, lc := range ac.AuditLogConfigs {
+			members := append([]string{auditLogConfigMember}, lc.ExemptedMembers...)
+			sort.Strings(members)
+			bindings = append(bindings, converter.IAMBinding{
+				Role:    auditLogConfigRole(ac.Service, lc.LogType),
+				Members: members,
+			})
+		}
+	}
+	return bindings
+}
+
+// splitAuditConfigs separates the bindings keeping audit configs from the
+// other bindings, and returns the audit configs sorted by service and log
+// type.
+func splitAuditConfigs(bindings []converter.IAMBinding) ([]converter.IAMBinding, []AuditConfig) {
+	var others []converter.IAMBinding
+	byService := make(map[string]*AuditConfig)
+	for _, b := range bindings {
+		service, logType, ok := splitAuditLogConfigRole(b.Role)
+		if !ok {
+			others = append(others, b)
+			continue
+		}
+		ac, ok := byService[service]
+		if !ok {
+			ac = &AuditConfig{Service: service}
+			byService[service] = ac
+		}
+		lc := AuditLogConfig{LogType: logType}
+		for _, m := range b.Members {
+			if m != auditLogConfigMember {
+				lc.ExemptedMembers = append(lc.ExemptedMembers, m)
+			}
+		}
+		ac.AuditLogConfigs = append(ac.AuditLogConfigs, lc)
+	}
+
+	configs := make([]AuditConfig, 0, len(byService))
+	for _, ac := range byService {
+		sort.Slice(ac.AuditLogConfigs, func(i, j int) bool {
+			return ac.AuditLogConfigs[i].LogType < ac.AuditLogConfigs[j].LogType
+		})
+		configs = append(configs, *ac)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Service < configs[j].Service })
+	if len(configs) == 0 {
+		configs = nil
+	}
+	return others, configs
+}
+
+// withoutServices returns the bindings without the audit log configs of the
+// given services.
+func withoutServices(bindings []converter.IAMBinding, services map[string]bool) []converter.IAMBinding {
+	var result []converter.IAMBinding
+	for _, b := range bindings {
+		if service, _, ok := splitAuditLogConfigRole(b.Role); ok && services[service] {
+			continue
+		}
+		result = append(result, b)
+	}
+	return result
+}
+
+// auditConfigServices returns the services of the audit configs kept in
+// the bindings of an asset.
+func auditConfigServices(asset converter.Asset) map[string]bool {
+	services := make(map[string]bool)
+	if asset.IAMPolicy == nil {
+		return services
+	}
+	for _, b := range asset.IAMPolicy.Bindings {
+		if service, _, ok := splitAuditLogConfigRole(b.Role); ok {
+			services[service] = true
+		}
+	}
+	return services
+}
+
+// auditConfigResource describes a google_*_iam_audit_config resource type.
+type auditConfigResource struct {
+	assetType string
+	// assetName returns the name of the asset the audit config is set on.
+	assetName  func(d converter.TerraformResourceData, config *converter.Config) (string, error)
+	newUpdater func(d converter.TerraformResourceData, config *converter.Config) (converter.ResourceIamUpdater, error)
+}
+
+var auditConfigResources = map[string]auditConfigResource{
+	"google_project_iam_audit_config": {
+		assetType: "cloudresourcemanager.googleapis.com/Project",
+		assetName: func(d converter.TerraformResourceData, config *converter.Config) (string, error) {
+			project, err := getProjectFromSchema("project", d, config)
+			if err != nil {
+				return "", err
+			}
+			return "//cloudresourcemanager.googleapis.com/projects/" + project, nil
+		},
+		newUpdater: converter.NewProjectIamUpdater,
+	},
+	"google_folder_iam_audit_config": {
+		assetType: "cloudresourcemanager.googleapis.com/Folder",
+		assetName: func(d converter.TerraformResourceData, config *converter.Config) (string, error) {
+			folder, ok := d.GetOk("folder")
+			if !ok {
+				return "", fmt.Errorf("required field 'folder' is not set")
+			}
+			if !strings.HasPrefix(folder.(string), "folders/") {
+				folder = "folders/" + folder.(string)
+			}
+			return "//cloudresourcemanager.googleapis.com/" + folder.(string), nil
+		},
+		newUpdater: converter.NewFolderIamUpdater,
+	},
+	"google_organization_iam_audit_config": {
+		assetType: "cloudresourcemanager.googleapis.com/Organization",
+		assetName: func(d converter.TerraformResourceData, config *converter.Config) (string, error) {
+			org, ok := d.GetOk("org_id")
+			if !ok {
+				return "", fmt.Errorf("required field 'org_id' is not set")
+			}
+			return "//cloudresourcemanager.googleapis.com/organizations/" + org.(string), nil
+		},
+		newUpdater: converter.NewOrganizationIamUpdater,
+	},
+}
+
+// auditConfigMappers returns the mappers of the google_*_iam_audit_config
+// resources, which the conversion library does not support. An audit config
+// resource is authoritative for the audit configs of its service.
+func auditConfigMappers() map[string][]converter.Mapper {
+	mappers := make(map[string][]converter.Mapper, len(auditConfigResources))
+	for kind, r := range auditConfigResources {
+		r := r
+		mappers[kind] = []converter.Mapper{{
+			Convert:           r.convert,
+			Fetch:             r.fetch,
+			MergeCreateUpdate: mergeAuditConfig,
+			MergeDelete:       mergeDeleteAuditConfig,
+		}}
+	}
+	return mappers
+}
+
+func (r auditConfigResource) convert(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	name, err := r.assetName(d, config)
+	if err != nil {
+		return nil, err
+	}
+	ac := AuditConfig{Service: d.Get("service").(string)}
+	if v, ok := d.GetOk("audit_log_config"); ok {
+		for _, raw := range v.(*schema.Set).List() {
+			m := raw.(map[string]interface{})
+			lc := AuditLogConfig{LogType: m["log_type"].(string)}
+			if members, ok := m["exempted_members"].(*schema.Set); ok {
+				for _, member := range members.List() {
+					lc.ExemptedMembers = append(lc.ExemptedMembers, member.(string))
+				}
+			}
+			ac.AuditLogConfigs = append(ac.AuditLogConfigs, lc)
+		}
+	}
+	return []converter.Asset{{
+		Name: name,
+		Type: r.assetType,
+		IAMPolicy: &converter.IAMPolicy{
+			Bindings: auditConfigBindings([]AuditConfig{ac}),
+		},
+	}}, nil
+}
+
+// fetch reads the IAM policy of the asset, with its audit configs and the
+// conditions of its bindings.
+func (r auditConfigResource) fetch(d converter.TerraformResourceData, config *converter.Config) (converter.Asset, error) {
+	name, err := r.assetName(d, config)
+	if err != nil {
+		return converter.Asset{}, err
+	}
+	updater, err := r.newUpdater(d, config)
+	if err != nil {
+		return converter.Asset{}, err
+	}
+	policy, err := updater.GetResourceIamPolicy()
+	if err != nil {
+		return converter.Asset{}, err
+	}
+	return converter.Asset{
+		Name: name,
+		Type: r.assetType,
+		IAMPolicy: &converter.IAMPolicy{
+			Bindings: policyBindings(policy),
+		},
+	}, nil
+}
+
+// policyBindings returns the bindings of a converter.IAMPolicy keeping the
+// bindings, conditions and audit configs of a policy.
+func policyBindings(policy *cloudresourcemanager.Policy) []converter.IAMBinding {
+	var bindings []converter.IAMBinding
+	for _, b := range policy.Bindings {
+		var cond *IAMCondition
+		if b.Condition != nil {
+			cond = &IAMCondition{
+				Title:       b.Condition.Title,
+				Description: b.Condition.Description,
+				Expression:  b.Condition.Expression,
+			}
+		}
+		bindings = append(bindings, converter.IAMBinding{
+			Role:    conditionalRole(b.Role, cond),
+			Members: b.Members,
+		})
+	}
+	return append(bindings, auditConfigBindings(policyAuditConfigs(policy))...)
+}
+
+func policyAuditConfigs(policy *cloudresourcemanager.Policy) []AuditConfig {
+	var configs []AuditConfig
+	for _, ac := range policy.AuditConfigs {
+		config := AuditConfig{Service: ac.Service}
+		for _, lc := range ac.AuditLogConfigs {
+			config.AuditLogConfigs = append(config.AuditLogConfigs, AuditLogConfig{
+				LogType:         lc.LogType,
+				ExemptedMembers: lc.ExemptedMembers,
+			})
+		}
+		configs = append(configs, config)
+	}
+	return configs
+}
+
+// mergeAuditConfig replaces the audit configs of the services of incoming
+// in existing.
+func mergeAuditConfig(existing, incoming converter.Asset) converter.Asset {
+	var bindings []converter.IAMBinding
+	if existing.IAMPolicy != nil {
+		bindings = withoutServices(existing.IAMPolicy.Bindings, auditConfigServices(incoming))
+	}
+	existing.IAMPolicy = &converter.IAMPolicy{
+		Bindings: append(bindings, incoming.IAMPolicy.Bindings...),
+	}
+	return existing
+}
+
+// mergeDeleteAuditConfig removes the audit configs of the services of
+// incoming from existing.
+func mergeDeleteAuditConfig(existing, incoming converter.Asset) converter.Asset {
+	if existing.IAMPolicy != nil {
+		existing.IAMPolicy = &converter.IAMPolicy{
+			Bindings: withoutServices(existing.IAMPolicy.Bindings, auditConfigServices(incoming)),
+		}
+	}
+	return existing
+}
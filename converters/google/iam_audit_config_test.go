@@ -0,0 +1,159 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddResourceChanges_auditConfigs(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	allServices := map[string]interface{}{
+		"project":          testProject,
+		"service":          "allServices",
+		"audit_log_config": []interface{}{map[string]interface{}{"log_type": "ADMIN_READ"}},
+	}
+	alice := map[string]interface{}{"project": testProject, "role": "roles/viewer", "member": "user:alice@example.com"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_project_iam_audit_config", "storage", create, map[string]interface{}{
+			"project": testProject,
+			"service": "storage.googleapis.com",
+			"audit_log_config": []interface{}{
+				map[string]interface{}{"log_type": "DATA_WRITE"},
+				map[string]interface{}{"log_type": "DATA_READ", "exempted_members": []interface{}{"user:bob@example.com", "user:alice@example.com"}},
+			},
+		}),
+		newTestResourceChange("google_project_iam_audit_config", "all", create, allServices),
+		newTestResourceChange("google_project_iam_member", "alice", create, alice),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "cloudresourcemanager.googleapis.com/Project", assets[0].Type)
+	assert.Equal(t, &IAMPolicy{
+		Bindings: []IAMBinding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+		AuditConfigs: []AuditConfig{
+			{Service: "allServices", AuditLogConfigs: []AuditLogConfig{{LogType: "ADMIN_READ"}}},
+			{Service: "storage.googleapis.com", AuditLogConfigs: []AuditLogConfig{
+				{LogType: "DATA_READ", ExemptedMembers: []string{"user:alice@example.com", "user:bob@example.com"}},
+				{LogType: "DATA_WRITE"},
+			}},
+		},
+	}, assets[0].IAMPolicy)
+	assert.Equal(t, []string{`iam_policy.audit_configs["allServices"].audit_log_configs["ADMIN_READ"]`}, assets[0].Provenance[1].Fields)
+
+	// An audit config replaces the audit configs of its service.
+	mapper := c.mapperFuncs["google_project_iam_audit_config"][0]
+	update := newTestResourceChange("google_project_iam_audit_config", "storage", tfjson.Actions{"update"}, map[string]interface{}{
+		"project":          testProject,
+		"service":          "storage.googleapis.com",
+		"audit_log_config": []interface{}{map[string]interface{}{"log_type": "DATA_READ"}},
+	})
+	rd, err := c.newResourceData(update, update.Change.After)
+	require.NoError(t, err)
+	converted, err := mapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	augmented, err := c.augmentAsset(rd, c.cfg, mapper.MergeCreateUpdate(assets[0].converterAsset, converted[0]))
+	require.NoError(t, err)
+	assert.Equal(t, []AuditConfig{
+		{Service: "allServices", AuditLogConfigs: []AuditLogConfig{{LogType: "ADMIN_READ"}}},
+		{Service: "storage.googleapis.com", AuditLogConfigs: []AuditLogConfig{{LogType: "DATA_READ"}}},
+	}, augmented.IAMPolicy.AuditConfigs)
+
+	// Deleting an audit config removes the audit configs of its service, and
+	// deleting a member keeps the audit configs.
+	del := newTestResourceChange("google_project_iam_audit_config", "all", tfjson.Actions{"delete"}, allServices)
+	rd, err = c.newResourceData(del, del.Change.Before)
+	require.NoError(t, err)
+	deleted, err := mapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	merged := mapper.MergeDelete(augmented.converterAsset, deleted[0])
+	delMember := newTestResourceChange("google_project_iam_member", "alice", tfjson.Actions{"delete"}, alice)
+	rd, err = c.newResourceData(delMember, delMember.Change.Before)
+	require.NoError(t, err)
+	memberMapper := c.mapperFuncs["google_project_iam_member"][0]
+	deleted, err = memberMapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	merged = memberMapper.MergeDelete(merged, deleted[0])
+	augmented, err = c.augmentAsset(rd, c.cfg, merged)
+	require.NoError(t, err)
+	assert.Equal(t, &IAMPolicy{
+		AuditConfigs: []AuditConfig{
+			{Service: "storage.googleapis.com", AuditLogConfigs: []AuditLogConfig{{LogType: "DATA_READ"}}},
+		},
+	}, augmented.IAMPolicy)
+}
+
+func TestAddResourceChanges_iamPolicyAuditConfigs(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	rc := &tfjson.ResourceChange{
+		Address:      "google_project_iam_policy.policy",
+		Mode:         "managed",
+		Type:         "google_project_iam_policy",
+		Name:         "policy",
+		ProviderName: "google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"project": testProject,
+				"policy_data": `{"bindings":[{"role":"roles/viewer","members":["user:alice@example.com"]}],` +
+					`"auditConfigs":[{"service":"allServices","auditLogConfigs":[{"logType":"DATA_READ","exemptedMembers":["user:alice@example.com"]}]}]}`,
+			},
+		},
+	}
+	require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, &IAMPolicy{
+		Bindings: []IAMBinding{{Role: "roles/viewer", Members: []string{"user:alice@example.com"}}},
+		AuditConfigs: []AuditConfig{
+			{Service: "allServices", AuditLogConfigs: []AuditLogConfig{{LogType: "DATA_READ", ExemptedMembers: []string{"user:alice@example.com"}}}},
+		},
+	}, assets[0].IAMPolicy)
+}
+
+func TestAuditConfigAssetNames(t *testing.T) {
+	cases := []struct {
+		kind   string
+		values map[string]interface{}
+		want   string
+	}{
+		{"google_project_iam_audit_config", map[string]interface{}{"service": "allServices"}, "//cloudresourcemanager.googleapis.com/projects/test-project"},
+		{"google_folder_iam_audit_config", map[string]interface{}{"service": "allServices", "folder": "123"}, "//cloudresourcemanager.googleapis.com/folders/123"},
+		{"google_folder_iam_audit_config", map[string]interface{}{"service": "allServices", "folder": "folders/123"}, "//cloudresourcemanager.googleapis.com/folders/123"},
+		{"google_organization_iam_audit_config", map[string]interface{}{"service": "allServices", "org_id": "456"}, "//cloudresourcemanager.googleapis.com/organizations/456"},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	for _, tc := range cases {
+		t.Run(tc.want, func(t *testing.T) {
+			rc := &tfjson.ResourceChange{Type: tc.kind, Change: &tfjson.Change{Actions: tfjson.Actions{"create"}, After: tc.values}}
+			rd, err := c.newResourceData(rc, tc.values)
+			require.NoError(t, err)
+			assets, err := c.mapperFuncs[tc.kind][0].Convert(rd, c.cfg)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, assets[0].Name)
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	cloudresourcemanager "google.golang.org/api/cloudresourcemanager/v1"
+)
+
+// IAMCondition is the condition of a conditional IAM binding.
+type IAMCondition struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Expression  string `json:"expression"`
+}
+
+// conditionSeparator separates the role of a conditional binding from its
+// condition in the role of converter.IAMBinding, which has no condition.
+// Roles never contain it.
+const conditionSeparator = "\n"
+
+// conditionalRole returns the role of a converter.IAMBinding for a binding
+// with a condition. The merge functions of the conversion library match
+// bindings by role, so bindings with the same role and different conditions
+// stay separate bindings.
+func conditionalRole(role string, cond *IAMCondition) string {
+	if cond == nil {
+		return role
+	}
+	b, _ := json.Marshal(cond)
+	return role + conditionSeparator + string(b)
+}
+
+// splitConditionalRole returns the role and condition of a role returned by
+// conditionalRole. It fails if the condition is malformed, e.g. for a role
+// of the plan containing a newline.
+func splitConditionalRole(role string) (string, *IAMCondition, error) {
+	i := strings.Index(role, conditionSeparator)
+	if i < 0 {
+		return role, nil, nil
+	}
+	cond := &IAMCondition{}
+	if err := json.Unmarshal([]byte(role[i+len(conditionSeparator):]), cond); err != nil {
+		return "", nil, fmt.Errorf("malformed condition in role %q: %w", role, err)
+	}
+	return role[:i], cond, nil
+}
+
+// splitConditionalBindings returns the bindings of a converter.IAMPolicy with
+// their conditions.
+func splitConditionalBindings(bindings []converter.IAMBinding) ([]IAMBinding, error) {
+	var result []IAMBinding
+	for _, b := range bindings {
+		role, cond, err := splitConditionalRole(b.Role)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, IAMBinding{
+			Role:      role,
+			Members:   b.Members,
+			Condition: cond,
+		})
+	}
+	return result, nil
+}
+
+// conditionalBindings returns the bindings of a converter.IAMPolicy for
+// bindings with conditions, see conditionalRole.
+func conditionalBindings(bindings []IAMBinding) []converter.IAMBinding {
+	var result []converter.IAMBinding
+	for _, b := range bindings {
+		result = append(result, converter.IAMBinding{
+			Role:    conditionalRole(b.Role, b.Condition),
+			Members: b.Members,
+		})
+	}
+	return result
+}
+
+// bindingField describes a binding in the fields of a Provenance.
+func bindingField(b IAMBinding) string {
+	if b.Condition == nil {
+		return fmt.Sprintf("%q", b.Role)
+	}
+	desc := b.Condition.Title
+	if desc == "" {
+		desc = b.Condition.Expression
+	}
+	return fmt.Sprintf("%q, condition %q", b.Role, desc)
+}
+
+// withIAMDetails wraps the Convert and Fetch functions of the mappers to keep
+// the conditions of IAM bindings and the audit configs of IAM policies, which
+// the conversion library drops.
+func withIAMDetails(mappers map[string][]converter.Mapper) map[string][]converter.Mapper {
+	for kind, ms := range mappers {
+		for i := range ms {
+			convert := ms[i].Convert
+			if ms[i].Fetch != nil && (strings.HasSuffix(kind, "_iam_member") || strings.HasSuffix(kind, "_iam_binding")) {
+				ms[i].Fetch = fetchIAMPolicy(kind, convert)
+			}
+			ms[i].Convert = func(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+				assets, err := convert(d, config)
+				if err != nil {
+					return nil, err
+				}
+				setIAMConditions(d, assets)
+				setPolicyAuditConfigs(d, assets)
+				return assets, nil
+			}
+		}
+	}
+	return mappers
+}
+
+type newIAMUpdaterFunc func(d converter.TerraformResourceData, config *converter.Config) (converter.ResourceIamUpdater, error)
+
+// iamUpdaters are the IAM updaters of the resources whose IAM policy the
+// conversion library fetches, by the prefix of their IAM resource types
+// (e.g. "google_storage_bucket" for google_storage_bucket_iam_member).
+var iamUpdaters = map[string]newIAMUpdaterFunc{
+	"google_bigquery_table":                converter.BigQueryTableIamUpdaterProducer,
+	"google_binary_authorization_attestor": converter.BinaryAuthorizationAttestorIamUpdaterProducer,
+	"google_cloudfunctions_function":       converter.CloudFunctionsCloudFunctionIamUpdaterProducer,
+	"google_compute_disk":                  converter.ComputeDiskIamUpdaterProducer,
+	"google_compute_image":                 converter.ComputeImageIamUpdaterProducer,
+	"google_compute_instance":              converter.ComputeInstanceIamUpdaterProducer,
+	"google_compute_region_disk":           converter.ComputeRegionDiskIamUpdaterProducer,
+	"google_compute_subnetwork":            converter.ComputeSubnetworkIamUpdaterProducer,
+	"google_data_catalog_entry_group":      converter.DataCatalogEntryGroupIamUpdaterProducer,
+	"google_data_catalog_tag_template":     converter.DataCatalogTagTemplateIamUpdaterProducer,
+	"google_endpoints_service":             converter.ServiceManagementServiceIamUpdaterProducer,
+	"google_folder":                        converter.NewFolderIamUpdater,
+	"google_healthcare_consent_store":      converter.HealthcareConsentStoreIamUpdaterProducer,
+	"google_iap_tunnel":                    converter.IapTunnelIamUpdaterProducer,
+	"google_iap_tunnel_instance":           converter.IapTunnelInstanceIamUpdaterProducer,
+	"google_iap_web":                       converter.IapWebIamUpdaterProducer,
+	"google_notebooks_instance":            converter.NotebooksInstanceIamUpdaterProducer,
+	"google_organization":                  converter.NewOrganizationIamUpdater,
+	"google_project":                       converter.NewProjectIamUpdater,
+	"google_pubsub_topic":                  converter.PubsubTopicIamUpdaterProducer,
+	"google_secret_manager_secret":         converter.SecretManagerSecretIamUpdaterProducer,
+	"google_storage_bucket":                converter.StorageBucketIamUpdaterProducer,
+}
+
+// fetchIAMPolicy returns the Fetch function of an IAM resource type. It reads
+// the IAM policy of the asset the resource converts into with the conditions
+// of its bindings and its audit configs, which the Fetch functions of the
+// conversion library drop: merging with such a policy would drop or widen its
+// conditional bindings. Resource types with no known IAM updater fail to
+// fetch instead.
+func fetchIAMPolicy(kind string, convert converter.ConvertFunc) converter.FetchFunc {
+	return func(d converter.TerraformResourceData, config *converter.Config) (converter.Asset, error) {
+		newUpdater, ok := iamUpdaters[strings.SplitN(kind, "_iam_", 2)[0]]
+		if !ok {
+			return converter.Asset{}, fmt.Errorf("fetching the IAM policy of %s: its conditional bindings cannot be fetched", kind)
+		}
+		assets, err := convert(d, config)
+		if err != nil {
+			return converter.Asset{}, err
+		}
+		if len(assets) != 1 {
+			return converter.Asset{}, fmt.Errorf("fetching the IAM policy of %s: converted into %d assets, want 1", kind, len(assets))
+		}
+		updater, err := newUpdater(d, config)
+		if err != nil {
+			return converter.Asset{}, err
+		}
+		policy, err := updater.GetResourceIamPolicy()
+		if err != nil {
+			return converter.Asset{}, err
+		}
+		return converter.Asset{
+			Name: assets[0].Name,
+			Type: assets[0].Type,
+			IAMPolicy: &converter.IAMPolicy{
+				Bindings: policyBindings(policy),
+			},
+		}, nil
+	}
+}
+
+// setIAMConditions sets the conditions of the bindings of the converted
+// assets of an IAM resource: the condition block of _iam_member and
+// _iam_binding resources, or the conditions in the policy_data of
+// _iam_policy resources.
+func setIAMConditions(d converter.TerraformResourceData, assets []converter.Asset) {
+	if pd, ok := d.GetOk("policy_data"); ok {
+		policy := &cloudresourcemanager.Policy{}
+		if err := json.Unmarshal([]byte(pd.(string)), policy); err != nil {
+			// The conversion library reports invalid policies.
+			return
+		}
+		for _, a := range assets {
+			if a.IAMPolicy == nil || len(a.IAMPolicy.Bindings) != len(policy.Bindings) {
+				continue
+			}
+			for i, b := range policy.Bindings {
+				if b.Condition == nil || a.IAMPolicy.Bindings[i].Role != b.Role {
+					continue
+				}
+				a.IAMPolicy.Bindings[i].Role = conditionalRole(b.Role, &IAMCondition{
+					Title:       b.Condition.Title,
+					Description: b.Condition.Description,
+					Expression:  b.Condition.Expression,
+				})
+			}
+		}
+		return
+	}
+
+	expression, ok := d.GetOk("condition.0.expression")
+	if !ok {
+		return
+	}
+	cond := &IAMCondition{
+		Title:       stringValue(d.Get("condition.0.title")),
+		Description: stringValue(d.Get("condition.0.description")),
+		Expression:  stringValue(expression),
+	}
+	for _, a := range assets {
+		if a.IAMPolicy == nil {
+			continue
+		}
+		for i, b := range a.IAMPolicy.Bindings {
+			a.IAMPolicy.Bindings[i].Role = conditionalRole(b.Role, cond)
+		}
+	}
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// setPolicyAuditConfigs adds the audit configs in the policy_data of
+// _iam_policy resources to their converted assets.
+func setPolicyAuditConfigs(d converter.TerraformResourceData, assets []converter.Asset) {
+	pd, ok := d.GetOk("policy_data")
+	if !ok {
+		return
+	}
+	policy := &cloudresourcemanager.Policy{}
+	if err := json.Unmarshal([]byte(pd.(string)), policy); err != nil {
+		return
+	}
+	bindings := auditConfigBindings(policyAuditConfigs(policy))
+	for _, a := range assets {
+		if a.IAMPolicy != nil {
+			a.IAMPolicy.Bindings = append(a.IAMPolicy.Bindings, bindings...)
+		}
+	}
+}
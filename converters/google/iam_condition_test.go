@@ -0,0 +1,152 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"strings"
+	"testing"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCondition = &IAMCondition{
+	Title:       "expires",
+	Description: "Expires at the end of 2021",
+	Expression:  `request.time < timestamp("2022-01-01T00:00:00Z")`,
+}
+
+// testConditionBlock is testCondition as the condition block of an IAM member.
+var testConditionBlock = []interface{}{map[string]interface{}{
+	"title":       testCondition.Title,
+	"description": testCondition.Description,
+	"expression":  testCondition.Expression,
+}}
+
+func TestConditionalRole(t *testing.T) {
+	role, cond, err := splitConditionalRole(conditionalRole("roles/viewer", testCondition))
+	require.NoError(t, err)
+	assert.Equal(t, "roles/viewer", role)
+	assert.Equal(t, testCondition, cond)
+
+	role, cond, err = splitConditionalRole(conditionalRole("roles/viewer", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "roles/viewer", role)
+	assert.Nil(t, cond)
+
+	_, _, err = splitConditionalRole("roles/viewer\nroles/editor")
+	assert.Error(t, err)
+}
+
+func TestAddResourceChanges_malformedRole(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_project_iam_member", "m", tfjson.Actions{"create"}, map[string]interface{}{
+			"project": testProject, "role": "roles/viewer\nroles/editor", "member": "user:alice@example.com",
+		}),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "malformed condition")
+}
+
+func TestAddResourceChanges_iamConditions(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	bob := map[string]interface{}{
+		"project": testProject, "role": "roles/viewer", "member": "user:bob@example.com", "condition": testConditionBlock,
+	}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_project_iam_member", "alice", create, map[string]interface{}{
+			"project": testProject, "role": "roles/viewer", "member": "user:alice@example.com",
+		}),
+		newTestResourceChange("google_project_iam_member", "bob", create, bob),
+		newTestResourceChange("google_project_iam_member", "carol", create, map[string]interface{}{
+			"project": testProject, "role": "roles/viewer", "member": "user:carol@example.com", "condition": testConditionBlock,
+		}),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, []IAMBinding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+		{Role: "roles/viewer", Members: []string{"user:bob@example.com", "user:carol@example.com"}, Condition: testCondition},
+	}, assets[0].IAMPolicy.Bindings)
+	assert.Equal(t, []string{`iam_policy.bindings["roles/viewer", condition "expires"].members["user:bob@example.com"]`}, assets[0].Provenance[1].Fields)
+
+	// Deleting the conditional member leaves the unconditional binding.
+	del := newTestResourceChange("google_project_iam_member", "bob", tfjson.Actions{"delete"}, bob)
+	rd, err := c.newResourceData(del, del.Change.Before)
+	require.NoError(t, err)
+	mapper := c.mapperFuncs["google_project_iam_member"][0]
+	deleted, err := mapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	merged := mapper.MergeDelete(assets[0].converterAsset, deleted[0])
+	augmented, err := c.augmentAsset(rd, c.cfg, merged)
+	require.NoError(t, err)
+	assert.Equal(t, []IAMBinding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+		{Role: "roles/viewer", Members: []string{"user:carol@example.com"}, Condition: testCondition},
+	}, augmented.IAMPolicy.Bindings)
+}
+
+func TestAddResourceChanges_iamPolicyConditions(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	rc := &tfjson.ResourceChange{
+		Address:      "google_project_iam_policy.policy",
+		Mode:         "managed",
+		Type:         "google_project_iam_policy",
+		Name:         "policy",
+		ProviderName: "google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"project": testProject,
+				"policy_data": `{"bindings":[` +
+					`{"role":"roles/viewer","members":["user:alice@example.com"]},` +
+					`{"role":"roles/viewer","members":["user:bob@example.com"],"condition":{"title":"expires","description":"Expires at the end of 2021","expression":"request.time < timestamp(\"2022-01-01T00:00:00Z\")"}}]}`,
+			},
+		},
+	}
+	require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, []IAMBinding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+		{Role: "roles/viewer", Members: []string{"user:bob@example.com"}, Condition: testCondition},
+	}, assets[0].IAMPolicy.Bindings)
+}
+
+func TestIAMUpdaters(t *testing.T) {
+	// Every IAM policy the conversion library fetches is fetched again
+	// with its conditions.
+	for kind, ms := range converter.Mappers() {
+		if ms[0].Fetch == nil || !strings.Contains(kind, "_iam_") {
+			continue
+		}
+		_, ok := iamUpdaters[strings.SplitN(kind, "_iam_", 2)[0]]
+		assert.True(t, ok, "no IAM updater for %s", kind)
+	}
+
+	fetch := fetchIAMPolicy("google_acme_thing_iam_member", nil)
+	_, err := fetch(nil, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,351 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// typeObject is the type addrToSchema uses for nested objects, i.e. the
+// resource itself and the elements of blocks. It mirrors the unexported
+// type of the same name in the SDK.
+const typeObject schema.ValueType = 999
+
+// jsonFieldReader is a schema.FieldReader that reads fields directly from
+// the decoded JSON values of a resource (e.g. the "after" values of a
+// resource change), converting them to the types of the resource schema.
+//
+// It reads the same results as flattening the values into a flatmap and
+// reading them with a schema.MapFieldReader, without formatting and parsing
+// every value: null values read as their default or zero value, numbers keep
+// their precision if they are decoded as json.Number, and values that do not
+// match the schema are dropped.
+type jsonFieldReader struct {
+	values map[string]interface{}
+	schema map[string]*schema.Schema
+}
+
+func (r *jsonFieldReader) ReadField(address []string) (schema.FieldReadResult, error) {
+	schemaList := addrToSchema(address, r.schema)
+	if len(schemaList) == 0 {
+		return schema.FieldReadResult{}, nil
+	}
+
+	// The number of elements of a list or set.
+	if n := len(address); n > 0 && address[n-1] == "#" {
+		v, ok := lookupValue(r.values, address[:n-1])
+		if !ok {
+			return schema.FieldReadResult{}, nil
+		}
+		list, _ := v.([]interface{})
+		return schema.FieldReadResult{Value: len(list), Exists: true}, nil
+	}
+
+	v, ok := lookupValue(r.values, address)
+	if !ok {
+		return schema.FieldReadResult{}, nil
+	}
+	return readValue(v, schemaList[len(schemaList)-1], address, nil)
+}
+
+// lookupValue finds the value at the given address, indexing objects and
+// maps by key and lists by position.
+func lookupValue(values map[string]interface{}, address []string) (interface{}, bool) {
+	var current interface{} = values
+	for _, k := range address {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			v, ok := c[k]
+			if !ok {
+				return nil, false
+			}
+			current = v
+		case []interface{}:
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			current = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// valueWalk collects what readValue finds while reading a whole resource.
+type valueWalk struct {
+	// dropped lists the attributes that are not in the schema or do not
+	// match its structure.
+	dropped []string
+	// unset holds the attributes that are null and have no default value.
+	unset map[string]bool
+}
+
+func (w *valueWalk) drop(path []string) {
+	if w != nil {
+		w.dropped = append(w.dropped, strings.Join(path, "."))
+	}
+}
+
+func (w *valueWalk) setUnset(path []string) {
+	if w != nil {
+		w.unset[strings.Join(path, ".")] = true
+	}
+}
+
+// errNotPrimitive is returned by toPrimitive for lists and objects.
+var errNotPrimitive = errors.New("not a primitive value")
+
+// readValue converts the JSON value v at path to the value a field reader
+// returns for the schema sch. If w is not nil, it records dropped and unset
+// attributes into w.
+func readValue(v interface{}, sch *schema.Schema, path []string, w *valueWalk) (schema.FieldReadResult, error) {
+	if v == nil {
+		dv, err := sch.DefaultValue()
+		if err != nil {
+			return schema.FieldReadResult{}, &AttributeError{Path: strings.Join(path, "."), Err: fmt.Errorf("getting default value: %w", err)}
+		}
+		if dv == nil {
+			w.setUnset(path)
+			if sch.Type == schema.TypeMap || sch.Type == typeObject {
+				return schema.FieldReadResult{}, nil
+			}
+			dv = sch.ZeroValue()
+		}
+		v = dv
+	}
+
+	switch sch.Type {
+	case schema.TypeBool, schema.TypeInt, schema.TypeFloat, schema.TypeString:
+		p, err := toPrimitive(v, sch.Type)
+		if err == errNotPrimitive {
+			w.drop(path)
+			return schema.FieldReadResult{}, nil
+		}
+		if err != nil {
+			return schema.FieldReadResult{}, &AttributeError{Path: strings.Join(path, "."), Err: err}
+		}
+		return schema.FieldReadResult{Value: p, Exists: true}, nil
+
+	case schema.TypeList, schema.TypeSet:
+		var items []interface{}
+		switch t := v.(type) {
+		case []interface{}:
+			items = t
+		case *schema.Set:
+			items = t.List()
+		default:
+			w.drop(path)
+			return schema.FieldReadResult{}, nil
+		}
+		elem := elemSchema(sch)
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			res, err := readValue(item, elem, append(path[:len(path):len(path)], strconv.Itoa(i)), w)
+			if err != nil {
+				return schema.FieldReadResult{}, err
+			}
+			if res.Exists {
+				list[i] = res.Value
+			}
+		}
+		if sch.Type == schema.TypeList {
+			return schema.FieldReadResult{Value: list, Exists: true}, nil
+		}
+		set := sch.ZeroValue().(*schema.Set)
+		for _, item := range list {
+			if item != nil {
+				set.Add(item)
+			}
+		}
+		return schema.FieldReadResult{Value: set, Exists: true}, nil
+
+	case schema.TypeMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			w.drop(path)
+			return schema.FieldReadResult{}, nil
+		}
+		if len(m) == 0 {
+			return schema.FieldReadResult{}, nil
+		}
+		elem := &schema.Schema{Type: mapValueType(sch)}
+		result := make(map[string]interface{}, len(m))
+		for k, e := range m {
+			res, err := readValue(e, elem, append(path[:len(path):len(path)], k), w)
+			if err != nil {
+				return schema.FieldReadResult{}, err
+			}
+			if res.Exists {
+				result[k] = res.Value
+			}
+		}
+		return schema.FieldReadResult{Value: result, Exists: true}, nil
+
+	case typeObject:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			w.drop(path)
+			return schema.FieldReadResult{}, nil
+		}
+		fields := sch.Elem.(map[string]*schema.Schema)
+		result := make(map[string]interface{}, len(fields))
+		exists := false
+		for field, s := range fields {
+			var res schema.FieldReadResult
+			if fv, ok := m[field]; ok {
+				var err error
+				res, err = readValue(fv, s, append(path[:len(path):len(path)], field), w)
+				if err != nil {
+					return schema.FieldReadResult{}, err
+				}
+			}
+			if res.Exists {
+				exists = true
+			}
+			result[field] = res.ValueOrZero(s)
+		}
+		if w != nil {
+			for k := range m {
+				// The "id" attribute and "timeouts" block of resources
+				// are not part of the schema.
+				if _, ok := fields[k]; !ok && !(len(path) == 0 && (k == "id" || k == "timeouts")) {
+					w.drop(append(path[:len(path):len(path)], k))
+				}
+			}
+		}
+		return schema.FieldReadResult{Value: result, Exists: exists}, nil
+
+	default:
+		return schema.FieldReadResult{}, &AttributeError{Path: strings.Join(path, "."), Err: fmt.Errorf("unknown schema type %v", sch.Type)}
+	}
+}
+
+// elemSchema returns the schema of the elements of a list or set, see
+// addrToSchema.
+func elemSchema(sch *schema.Schema) *schema.Schema {
+	switch e := sch.Elem.(type) {
+	case *schema.Resource:
+		return &schema.Schema{Type: typeObject, Elem: e.Schema}
+	case *schema.Schema:
+		return e
+	case schema.ValueType:
+		return &schema.Schema{Type: e}
+	default:
+		return &schema.Schema{Type: schema.TypeString}
+	}
+}
+
+// mapValueType returns the type of the values of a map, see the SDK's
+// getValueType.
+func mapValueType(sch *schema.Schema) schema.ValueType {
+	switch e := sch.Elem.(type) {
+	case schema.ValueType:
+		return e
+	case *schema.Schema:
+		return e.Type
+	default:
+		return schema.TypeString
+	}
+}
+
+// floatToInt converts a float to an int, failing for fractions and for
+// floats out of the range of an int64.
+func floatToInt(f float64) (interface{}, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return nil, fmt.Errorf("%v is not a 64-bit integer", f)
+	}
+	return int(f), nil
+}
+
+// toPrimitive converts a JSON (or default) value to the Go type of a
+// primitive schema type. It returns errNotPrimitive for lists and objects.
+func toPrimitive(v interface{}, t schema.ValueType) (interface{}, error) {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}, *schema.Set:
+		return nil, errNotPrimitive
+	}
+
+	switch t {
+	case schema.TypeString:
+		switch x := v.(type) {
+		case string:
+			return x, nil
+		case bool:
+			return strconv.FormatBool(x), nil
+		case json.Number:
+			return x.String(), nil
+		case float64:
+			return strconv.FormatFloat(x, 'f', -1, 64), nil
+		case int:
+			return strconv.Itoa(x), nil
+		}
+	case schema.TypeBool:
+		switch x := v.(type) {
+		case bool:
+			return x, nil
+		case string:
+			if x == "" {
+				return false, nil
+			}
+			return strconv.ParseBool(x)
+		}
+	case schema.TypeInt:
+		switch x := v.(type) {
+		case json.Number:
+			if i, err := x.Int64(); err == nil {
+				return int(i), nil
+			}
+			// Numbers like 1e3 are integers too, but not numbers that
+			// overflow an int64, which would silently lose precision.
+			f, err := x.Float64()
+			if err != nil {
+				return nil, err
+			}
+			return floatToInt(f)
+		case float64:
+			return floatToInt(x)
+		case float32:
+			return floatToInt(float64(x))
+		case int:
+			return x, nil
+		}
+		return nil, fmt.Errorf("expected a number, got %T", v)
+	case schema.TypeFloat:
+		switch x := v.(type) {
+		case json.Number:
+			return x.Float64()
+		case float64:
+			return x, nil
+		case float32:
+			return float64(x), nil
+		case int:
+			return float64(x), nil
+		case string:
+			if x == "" {
+				return 0.0, nil
+			}
+			return strconv.ParseFloat(x, 64)
+		}
+	}
+	return nil, fmt.Errorf("unrecognized type %T", v)
+}
@@ -0,0 +1,344 @@
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	provider "github.com/hashicorp/terraform-provider-google/v3/google"
+)
+
+// The flatmap reader below is how resource data used to be read: values are
+// flattened into a map[string]string and read back by schema.MapFieldReader.
+// It is kept as the reference for jsonFieldReader and for benchmarks.
+
+func newMapFieldReader(values map[string]interface{}, resourceSchema map[string]*schema.Schema) (schema.FieldReader, error) {
+	fm, err := newFlatmap(values, resourceSchema)
+	if err != nil {
+		return nil, err
+	}
+	return &schema.MapFieldReader{
+		Map:    schema.BasicMapReader(fm.state),
+		Schema: resourceSchema,
+	}, nil
+}
+
+// flatmap is the flattened form of resource values, see attributes.
+type flatmap struct {
+	state map[string]string
+	// dropped lists the attributes that are not in the schema.
+	dropped []string
+	// unset holds the attributes that are null and have no default value.
+	unset map[string]bool
+}
+
+func newFlatmap(values map[string]interface{}, schemas map[string]*schema.Schema) (*flatmap, error) {
+	fm := &flatmap{
+		state: map[string]string{},
+		unset: map[string]bool{},
+	}
+	var address []string
+	if err := attributes(values, address, fm, schemas); err != nil {
+		return nil, err
+	}
+	sort.Strings(fm.dropped)
+	return fm, nil
+}
+
+// attributes function takes json parsed JSON object (value param) and fill map[string]string with it's
+// content (state param) for example JSON:
+//
+//	{
+//		"foo": {
+//			"name" : "value"
+//		},
+//	  "list": ["item1", "item2"]
+//	}
+//
+// will be translated to map with following key/value set:
+//
+//	foo.name => "value"
+//	list.# => 2
+//	list.0 => "item1"
+//	list.1 => "item2"
+//
+// Map above will be passed to schema.BasicMapReader that have all appropriate logic to read fields
+// correctly during conversion to CAI.
+//
+// Attributes that are not in the schema are skipped and their addresses are
+// added to fm.dropped. The "timeouts" block is not part of the schema and is
+// skipped silently.
+func attributes(value interface{}, address []string, fm *flatmap, schemas map[string]*schema.Schema) error {
+	state := fm.state
+	schemaArr := addrToSchema(address, schemas)
+	if len(schemaArr) == 0 {
+		if len(address) != 1 || address[0] != "timeouts" {
+			fm.dropped = append(fm.dropped, strings.Join(address, "."))
+		}
+		return nil
+	}
+	sch := schemaArr[len(schemaArr)-1]
+	addr := strings.Join(address, ".")
+	// int is special case, can't use handle it in main switch because number will be always parsed from JSON as float
+	// need to identify it by schema.TypeInt and convert to int from int or float
+	if sch.Type == schema.TypeInt && value != nil {
+		switch value.(type) {
+		case int:
+			state[addr] = strconv.Itoa(value.(int))
+		case float64:
+			state[addr] = strconv.Itoa(int(value.(float64)))
+		case float32:
+			state[addr] = strconv.Itoa(int(value.(float32)))
+		default:
+			return &AttributeError{Path: addr, Err: fmt.Errorf("expected a number, got %T", value)}
+		}
+		return nil
+	}
+
+	switch value.(type) {
+	case nil:
+		defaultValue, err := sch.DefaultValue()
+		if err != nil {
+			return &AttributeError{Path: addr, Err: fmt.Errorf("getting default value: %w", err)}
+		}
+		if defaultValue == nil {
+			fm.unset[addr] = true
+			defaultValue = sch.ZeroValue()
+		}
+		return attributes(defaultValue, address, fm, schemas)
+	case float64:
+		state[addr] = strconv.FormatFloat(value.(float64), 'f', 6, 64)
+	case float32:
+		state[addr] = strconv.FormatFloat(float64(value.(float32)), 'f', 6, 32)
+	case string:
+		state[addr] = value.(string)
+	case bool:
+		state[addr] = strconv.FormatBool(value.(bool))
+	case int:
+		state[addr] = strconv.Itoa(value.(int))
+	case []interface{}:
+		arr := value.([]interface{})
+		countAddr := addr + ".#"
+		state[countAddr] = strconv.Itoa(len(arr))
+		for i, e := range arr {
+			addr := append(address, strconv.Itoa(i))
+			if err := attributes(e, addr, fm, schemas); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		m := value.(map[string]interface{})
+		for k, v := range m {
+			addr := append(address, k)
+			if err := attributes(v, addr, fm, schemas); err != nil {
+				return err
+			}
+		}
+	case *schema.Set:
+		set := value.(*schema.Set)
+		return attributes(set.List(), address, fm, schemas)
+	default:
+		return &AttributeError{Path: addr, Err: fmt.Errorf("unrecognized type %T", value)}
+	}
+	return nil
+}
+
+type planValues struct {
+	kind   string
+	values map[string]interface{}
+}
+
+// readPlanValues reads the "after" values of the resource changes of all
+// test plans, decoding numbers as float64 or json.Number.
+func readPlanValues(t testing.TB, useNumber bool) []planValues {
+	files, err := filepath.Glob("../../testdata/templates/*.tfplan.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result []planValues
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var plan struct {
+			ResourceChanges []struct {
+				Type   string `json:"type"`
+				Change struct {
+					After map[string]interface{} `json:"after"`
+				} `json:"change"`
+			} `json:"resource_changes"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		if useNumber {
+			dec.UseNumber()
+		}
+		if err := dec.Decode(&plan); err != nil {
+			t.Fatalf("decoding %s: %v", f, err)
+		}
+		for _, rc := range plan.ResourceChanges {
+			if rc.Change.After != nil {
+				result = append(result, planValues{kind: rc.Type, values: rc.Change.After})
+			}
+		}
+	}
+	return result
+}
+
+// readAllFields reads every top-level field like a mapper would.
+func readAllFields(t testing.TB, r schema.FieldReader, resourceSchema map[string]*schema.Schema) map[string]interface{} {
+	fields := make(map[string]interface{})
+	for k, s := range resourceSchema {
+		res, err := r.ReadField([]string{k})
+		if err != nil {
+			t.Fatalf("reading %s: %v", k, err)
+		}
+		fields[k] = res.ValueOrZero(s)
+	}
+	return fields
+}
+
+func TestJSONFieldReader_matchesMapFieldReader(t *testing.T) {
+	p := provider.Provider()
+	for _, pv := range readPlanValues(t, false) {
+		resource, ok := p.ResourcesMap[pv.kind]
+		if !ok {
+			continue
+		}
+		mapReader, err := newMapFieldReader(pv.values, resource.Schema)
+		if err != nil {
+			t.Fatalf("%s: %v", pv.kind, err)
+		}
+		jsonReader := &jsonFieldReader{values: pv.values, schema: resource.Schema}
+
+		want := readAllFields(t, mapReader, resource.Schema)
+		got := readAllFields(t, jsonReader, resource.Schema)
+		for k, w := range want {
+			if !equalFieldValues(w, got[k]) {
+				t.Errorf("%s: field %s = %#v, want %#v", pv.kind, k, got[k], w)
+			}
+		}
+	}
+}
+
+// equalFieldValues compares field values. Sets are compared by the hashes of
+// their elements, as reflect.DeepEqual cannot compare their hash functions.
+func equalFieldValues(a, b interface{}) bool {
+	return reflect.DeepEqual(normalizeSets(a), normalizeSets(b))
+}
+
+func normalizeSets(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *schema.Set:
+		m := make(map[int]interface{}, t.Len())
+		for _, e := range t.List() {
+			m[t.F(e)] = normalizeSets(e)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(t))
+		for i, e := range t {
+			l[i] = normalizeSets(e)
+		}
+		return l
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = normalizeSets(e)
+		}
+		return m
+	}
+	return v
+}
+
+func TestJSONFieldReader_numbers(t *testing.T) {
+	p := provider.Provider()
+	resourceSchema := p.ResourcesMap["google_compute_disk"].Schema
+
+	values := map[string]interface{}{
+		// Larger than the precision of a float64.
+		"size":                      json.Number("9007199254740993"),
+		"physical_block_size_bytes": json.Number("4096"),
+		"name":                      "test-disk",
+	}
+	d, err := NewFakeResourceData("google_compute_disk", resourceSchema, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Get("size"); got != 9007199254740993 {
+		t.Errorf("size = %v, want %v", got, 9007199254740993)
+	}
+	if got := d.Get("physical_block_size_bytes"); got != 4096 {
+		t.Errorf("physical_block_size_bytes = %v, want %v", got, 4096)
+	}
+}
+
+func TestJSONFieldReader_intOverflow(t *testing.T) {
+	p := provider.Provider()
+	resourceSchema := p.ResourcesMap["google_compute_disk"].Schema
+
+	for _, size := range []json.Number{"9223372036854775808", "1e19", "1.5"} {
+		values := map[string]interface{}{"size": size, "name": "test-disk"}
+		if _, err := NewFakeResourceData("google_compute_disk", resourceSchema, values); err == nil {
+			t.Errorf("NewFakeResourceData with size %v: got no error", size)
+		}
+	}
+
+	values := map[string]interface{}{"size": json.Number("1e3"), "name": "test-disk"}
+	d, err := NewFakeResourceData("google_compute_disk", resourceSchema, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Get("size"); got != 1000 {
+		t.Errorf("size = %v, want %v", got, 1000)
+	}
+}
+
+func BenchmarkFakeResourceData(b *testing.B) {
+	p := provider.Provider()
+	readers := []struct {
+		name      string
+		useNumber bool
+		newReader func(map[string]interface{}, map[string]*schema.Schema) (schema.FieldReader, error)
+	}{
+		{
+			name:      "flatmap",
+			newReader: newMapFieldReader,
+		},
+		{
+			name:      "json",
+			useNumber: true,
+			newReader: func(values map[string]interface{}, resourceSchema map[string]*schema.Schema) (schema.FieldReader, error) {
+				d, err := NewFakeResourceData("", resourceSchema, values)
+				return d.reader, err
+			},
+		},
+	}
+	for _, r := range readers {
+		plan := readPlanValues(b, r.useNumber)
+		b.Run(r.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, pv := range plan {
+					resource, ok := p.ResourcesMap[pv.kind]
+					if !ok {
+						continue
+					}
+					reader, err := r.newReader(pv.values, resource.Schema)
+					if err != nil {
+						b.Fatal(err)
+					}
+					readAllFields(b, reader, resource.Schema)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+)
+
+// globalLocation is the location of resources that are not regional or
+// zonal, as reported by CAI.
+const globalLocation = "global"
+
+// locationAttributes are the attributes holding the location of a resource,
+// from the most to the least specific.
+var locationAttributes = []string{"location", "zone", "region"}
+
+// assetLocation returns the normalized location of the resource converted
+// from tfData: the value of its location, zone or region attribute, lower
+// cased and without the self link prefix (e.g. "us-central1-a", "eu"). A
+// resource with a zone or region attribute that is not set is in the
+// provider's default zone or region. Any other resource is global.
+func assetLocation(tfData converter.TerraformResourceData, cfg *converter.Config) string {
+	for _, attr := range locationAttributes {
+		if v, ok := tfData.GetOk(attr); ok {
+			if s, ok := v.(string); ok && s != "" {
+				return normalizeLocation(s)
+			}
+		}
+	}
+	defaults := map[string]string{"zone": cfg.Zone, "region": cfg.Region}
+	for _, attr := range locationAttributes {
+		if defaults[attr] != "" && hasAttribute(tfData, attr) {
+			return normalizeLocation(defaults[attr])
+		}
+	}
+	return globalLocation
+}
+
+// mergeLocation returns the location of an asset that resources with the
+// locations a and b were merged into, whatever the order of the resources:
+// the location of the resource that has one rather than global, and the
+// first one in lexical order if they conflict.
+func mergeLocation(a, b string) string {
+	switch {
+	case a == "" || a == globalLocation && b != "":
+		return b
+	case b == "" || b == globalLocation:
+		return a
+	case b < a:
+		return b
+	}
+	return a
+}
+
+// normalizeLocation returns the lower-cased last segment of a location,
+// zone or region, which may be given as a self link.
+func normalizeLocation(location string) string {
+	return strings.ToLower(location[strings.LastIndex(location, "/")+1:])
+}
+
+// hasAttribute reports whether the schema of the resource data has the given
+// top-level attribute.
+func hasAttribute(tfData converter.TerraformResourceData, attr string) bool {
+	d, ok := tfData.(*FakeResourceData)
+	if !ok {
+		return false
+	}
+	_, ok = d.schema[attr]
+	return ok
+}
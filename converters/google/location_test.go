@@ -0,0 +1,158 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetLocation(t *testing.T) {
+	cases := []struct {
+		name   string
+		kind   string
+		values map[string]interface{}
+		want   string
+	}{
+		{
+			name:   "Location",
+			kind:   "google_storage_bucket",
+			values: map[string]interface{}{"name": "b", "location": "EU"},
+			want:   "eu",
+		},
+		{
+			name:   "ZoneSelfLink",
+			kind:   "google_compute_disk",
+			values: map[string]interface{}{"name": "d", "zone": "https://www.googleapis.com/compute/v1/projects/p/zones/europe-west1-b"},
+			want:   "europe-west1-b",
+		},
+		{
+			name:   "Region",
+			kind:   "google_sql_database_instance",
+			values: map[string]interface{}{"name": "i", "region": "europe-west4"},
+			want:   "europe-west4",
+		},
+		{
+			name:   "DefaultZone",
+			kind:   "google_compute_disk",
+			values: map[string]interface{}{"name": "d"},
+			want:   "us-east1-c",
+		},
+		{
+			name:   "DefaultRegion",
+			kind:   "google_compute_subnetwork",
+			values: map[string]interface{}{"name": "s"},
+			want:   "us-east1",
+		},
+		{
+			name:   "Global",
+			kind:   "google_compute_network",
+			values: map[string]interface{}{"name": "n"},
+			want:   "global",
+		},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	c.cfg.Region = "us-east1"
+	c.cfg.Zone = "us-east1-c"
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := &tfjson.ResourceChange{
+				Type:   tc.kind,
+				Change: &tfjson.Change{Actions: tfjson.Actions{"create"}, After: tc.values},
+			}
+			rd, err := c.newResourceData(rc, tc.values)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, assetLocation(rd, c.cfg))
+		})
+	}
+}
+
+func TestAddResourceChanges_mergedLocation(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		{
+			Address: "google_storage_bucket.b",
+			Type:    "google_storage_bucket",
+			Name:    "b",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After:   map[string]interface{}{"name": "b", "location": "EU", "project": testProject},
+			},
+		},
+		{
+			Address: "google_storage_bucket_iam_member.m",
+			Type:    "google_storage_bucket_iam_member",
+			Name:    "m",
+			Change: &tfjson.Change{
+				Actions: tfjson.Actions{"create"},
+				After:   map[string]interface{}{"bucket": "b", "role": "roles/storage.admin", "member": "user:jane@example.com"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	require.NotNil(t, assets[0].Resource)
+	require.NotNil(t, assets[0].IAMPolicy)
+	assert.Equal(t, "eu", assets[0].Resource.Location)
+}
+
+func TestSetProviderDefaults(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	c.envRegion = "us-east1"
+	c.envZone = "us-east1-c"
+	subnetwork := map[string]interface{}{"name": "s"}
+	rc := &tfjson.ResourceChange{
+		Type:   "google_compute_subnetwork",
+		Change: &tfjson.Change{Actions: tfjson.Actions{"create"}, After: subnetwork},
+	}
+	rd, err := c.newResourceData(rc, subnetwork)
+	require.NoError(t, err)
+
+	// The provider configuration of the plan comes first.
+	c.SetProviderDefaults("europe-west1", "")
+	assert.Equal(t, "europe-west1", assetLocation(rd, c.cfg))
+	assert.Equal(t, "us-east1-c", c.cfg.Zone)
+
+	c.SetProviderDefaults("", "")
+	assert.Equal(t, "us-east1", assetLocation(rd, c.cfg))
+}
+
+func TestMergeLocation(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want string
+	}{
+		{"", "", ""},
+		{"eu", "", "eu"},
+		{"eu", "global", "eu"},
+		{"global", "", "global"},
+		{"global", "global", "global"},
+		{"us", "eu", "eu"},
+		{"eu", "eu", "eu"},
+	}
+	for _, tc := range cases {
+		// The location of the merged asset does not depend on the order
+		// of the resources.
+		assert.Equal(t, tc.want, mergeLocation(tc.a, tc.b), "%q, %q", tc.a, tc.b)
+		assert.Equal(t, tc.want, mergeLocation(tc.b, tc.a), "%q, %q", tc.b, tc.a)
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Mapping declares how to convert a Terraform resource type into a CAI
+// asset, for resource types that terraform-google-conversion does not
+// support. For example:
+//
+//	terraform_type: google_foo_bar
+//	asset_type: foo.googleapis.com/Bar
+//	name: //foo.googleapis.com/projects/{{project}}/locations/{{location}}/bars/{{name}}
+//	version: v1
+//	discovery_name: Bar
+//	fields:
+//	  displayName: display_name
+//	  config.size: settings.0.size
+type Mapping struct {
+	TerraformType string `yaml:"terraform_type"`
+	AssetType     string `yaml:"asset_type"`
+	// Name is the template of the asset name. {{field}} is replaced with
+	// the value of the attribute, and {{project}}, {{region}} and {{zone}}
+	// fall back to the provider configuration.
+	Name                 string `yaml:"name"`
+	Version              string `yaml:"version"`
+	DiscoveryDocumentURI string `yaml:"discovery_document_uri"`
+	DiscoveryName        string `yaml:"discovery_name"`
+	// Fields maps paths in Resource.Data (e.g. "config.size") to attribute
+	// paths of the Terraform resource (e.g. "settings.0.size"). Attributes
+	// that are not set are left out.
+	Fields map[string]string `yaml:"fields"`
+
+	// File is the file the mapping was loaded from, if any.
+	File string `yaml:"-"`
+}
+
+// nameVar matches the variables of a name template.
+var nameVar = regexp.MustCompile(`{{([\w.]+)}}`)
+
+// LoadMappings loads the mappings in the .yaml and .yml files of dir, one
+// mapping per file.
+func LoadMappings(dir string) ([]*Mapping, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	var mappings []*Mapping
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening mapping file")
+		}
+		m := &Mapping{File: f}
+		if err := yaml.UnmarshalStrict(data, m); err != nil {
+			return nil, errors.Wrapf(err, "reading mapping from %s", f)
+		}
+		if err := m.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid mapping in %s", f)
+		}
+		mappings = append(mappings, m)
+	}
+	return mappings, nil
+}
+
+// Validate checks that the mapping has all required fields and that its
+// data paths do not overlap.
+func (m *Mapping) Validate() error {
+	switch {
+	case m.TerraformType == "":
+		return errors.New("terraform_type is required")
+	case m.AssetType == "":
+		return errors.New("asset_type is required")
+	case m.Name == "":
+		return errors.New("name is required")
+	}
+	for path, attr := range m.Fields {
+		if path == "" || attr == "" {
+			return fmt.Errorf("field %q: data path and attribute must not be empty", path)
+		}
+		for other := range m.Fields {
+			if strings.HasPrefix(other, path+".") {
+				return fmt.Errorf("field %q: overlaps with field %q", path, other)
+			}
+		}
+	}
+	return nil
+}
+
+// AddMappings registers the conversion of the resource types of the given
+// mappings next to the built-in mappers. Resource types that are not in the
+// provider schema are read with a schema inferred from their values.
+func (c *Converter) AddMappings(mappings []*Mapping) error {
+	for _, m := range mappings {
+		if _, ok := c.mapperFuncs[m.TerraformType]; ok {
+			return fmt.Errorf("mapping %s: resource type %s is already supported", m.File, m.TerraformType)
+		}
+	}
+	for _, m := range mappings {
+		c.mapperFuncs[m.TerraformType] = []converter.Mapper{{Convert: m.convert}}
+	}
+	return nil
+}
+
+// SupportedTerraformResources lists the resource types supported by the
+// built-in mappers and the given mappings and plugins.
+func SupportedTerraformResources(mappings []*Mapping, plugins []*Plugin) []string {
+	list := converter.SupportedTerraformResources()
+	for kind := range auditConfigResources {
+		list = append(list, kind)
+	}
+	for kind := range accessContextManagerMappers() {
+		list = append(list, kind)
+	}
+	for kind := range v2OrgPolicyMappers() {
+		list = append(list, kind)
+	}
+	for _, m := range mappings {
+		list = append(list, m.TerraformType)
+	}
+	for _, p := range plugins {
+		list = append(list, p.ResourceTypes...)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (m *Mapping) convert(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	name, err := m.assetName(d, config)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]interface{})
+	paths := make([]string, 0, len(m.Fields))
+	for path := range m.Fields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		v, ok := d.GetOkExists(m.Fields[path])
+		if !ok {
+			continue
+		}
+		setDataPath(data, strings.Split(path, "."), dataValue(v))
+	}
+
+	return []converter.Asset{{
+		Name: name,
+		Type: m.AssetType,
+		Resource: &converter.AssetResource{
+			Version:              m.Version,
+			DiscoveryDocumentURI: m.DiscoveryDocumentURI,
+			DiscoveryName:        m.DiscoveryName,
+			Data:                 data,
+		},
+	}}, nil
+}
+
+// assetName expands the name template of the mapping.
+func (m *Mapping) assetName(d converter.TerraformResourceData, config *converter.Config) (string, error) {
+	var err error
+	name := nameVar.ReplaceAllStringFunc(m.Name, func(s string) string {
+		field := nameVar.FindStringSubmatch(s)[1]
+		if v, ok := d.GetOkExists(field); ok {
+			if s := fmt.Sprint(v); s != "" {
+				return s
+			}
+		}
+		switch field {
+		case "project":
+			if config.Project != "" {
+				return config.Project
+			}
+		case "region":
+			if config.Region != "" {
+				return config.Region
+			}
+		case "zone":
+			if config.Zone != "" {
+				return config.Zone
+			}
+		}
+		if err == nil {
+			err = fmt.Errorf("name template %q: %s is not set", m.Name, field)
+		}
+		return ""
+	})
+	return name, err
+}
+
+// setDataPath sets the value at the given path of nested objects.
+func setDataPath(data map[string]interface{}, path []string, v interface{}) {
+	for _, k := range path[:len(path)-1] {
+		next, ok := data[k].(map[string]interface{})
+		if !ok {
+			next = make(map[string]interface{})
+			data[k] = next
+		}
+		data = next
+	}
+	data[path[len(path)-1]] = v
+}
+
+// dataValue converts a value read from resource data into a JSON value.
+func dataValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case *schema.Set:
+		return dataValue(t.List())
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, e := range t {
+			list[i] = dataValue(e)
+		}
+		return list
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = dataValue(e)
+		}
+		return m
+	}
+	return v
+}
+
+// inferSchema returns a schema that reads the given values of a resource
+// whose type is not in the provider schema: objects in lists are blocks,
+// other objects are maps.
+func inferSchema(values map[string]interface{}) map[string]*schema.Schema {
+	result := make(map[string]*schema.Schema, len(values))
+	for k, v := range values {
+		if s := inferValueSchema(v); s != nil {
+			result[k] = s
+		}
+	}
+	return result
+}
+
+func inferValueSchema(v interface{}) *schema.Schema {
+	switch t := v.(type) {
+	case nil, string:
+		return &schema.Schema{Type: schema.TypeString, Optional: true}
+	case bool:
+		return &schema.Schema{Type: schema.TypeBool, Optional: true}
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return &schema.Schema{Type: schema.TypeInt, Optional: true}
+		}
+		return &schema.Schema{Type: schema.TypeFloat, Optional: true}
+	case float64:
+		return &schema.Schema{Type: schema.TypeFloat, Optional: true}
+	case []interface{}:
+		objects := make(map[string]interface{})
+		var elem *schema.Schema
+		for _, e := range t {
+			if o, ok := e.(map[string]interface{}); ok {
+				for k, v := range o {
+					if objects[k] == nil {
+						objects[k] = v
+					}
+				}
+			} else if e != nil && elem == nil {
+				elem = inferValueSchema(e)
+			}
+		}
+		if len(objects) > 0 {
+			return &schema.Schema{Type: schema.TypeList, Optional: true, Elem: &schema.Resource{Schema: inferSchema(objects)}}
+		}
+		if elem == nil {
+			elem = &schema.Schema{Type: schema.TypeString}
+		}
+		return &schema.Schema{Type: schema.TypeList, Optional: true, Elem: elem}
+	case map[string]interface{}:
+		// Maps only hold primitive values. A map of values of different
+		// types is a map of strings, whatever the order of its keys.
+		var elem *schema.Schema
+		for _, e := range t {
+			if e == nil {
+				continue
+			}
+			s := inferValueSchema(e)
+			if s == nil || s.Type == schema.TypeList || s.Type == schema.TypeMap {
+				return nil
+			}
+			if elem != nil && elem.Type != s.Type {
+				s = &schema.Schema{Type: schema.TypeString, Optional: true}
+			}
+			if elem == nil || elem.Type != schema.TypeString {
+				elem = s
+			}
+		}
+		if elem == nil {
+			elem = &schema.Schema{Type: schema.TypeString}
+		}
+		return &schema.Schema{Type: schema.TypeMap, Optional: true, Elem: elem}
+	}
+	return nil
+}
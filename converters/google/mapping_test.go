@@ -0,0 +1,224 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMapping = `
+terraform_type: google_foo_bar
+asset_type: foo.googleapis.com/Bar
+name: //foo.googleapis.com/projects/{{project}}/locations/{{location}}/bars/{{name}}
+version: v1
+discovery_document_uri: https://foo.googleapis.com/$discovery/rest?version=v1
+discovery_name: Bar
+fields:
+  displayName: display_name
+  labels: labels
+  config.size: settings.0.size
+  config.tags: settings.0.tags
+  unset: not_set
+`
+
+func writeMappings(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "mappings")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoadMappings(t *testing.T) {
+	dir := writeMappings(t, map[string]string{
+		"foo_bar.yaml": testMapping,
+		"README.md":    "not a mapping",
+	})
+	mappings, err := LoadMappings(dir)
+	require.NoError(t, err)
+	require.Len(t, mappings, 1)
+	assert.Equal(t, "google_foo_bar", mappings[0].TerraformType)
+	assert.Equal(t, "settings.0.size", mappings[0].Fields["config.size"])
+	assert.Equal(t, filepath.Join(dir, "foo_bar.yaml"), mappings[0].File)
+}
+
+func TestLoadMappings_invalid(t *testing.T) {
+	cases := map[string]string{
+		"MissingAssetType": "terraform_type: google_foo_bar\nname: foo",
+		"UnknownKey":       testMapping + "unknown: true\n",
+		"OverlappingPaths": "terraform_type: google_foo_bar\nasset_type: foo.googleapis.com/Bar\nname: foo\nfields:\n  config: a\n  config.size: b\n",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := writeMappings(t, map[string]string{"mapping.yml": content})
+			_, err := LoadMappings(dir)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAddMappings(t *testing.T) {
+	mappings, err := LoadMappings(writeMappings(t, map[string]string{"foo_bar.yaml": testMapping}))
+	require.NoError(t, err)
+
+	rc := &tfjson.ResourceChange{
+		Address:      "google_foo_bar.default",
+		Mode:         "managed",
+		Type:         "google_foo_bar",
+		Name:         "default",
+		ProviderName: "registry.terraform.io/hashicorp/google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"name":         "my-bar",
+				"location":     "us-central1",
+				"display_name": "My bar",
+				"labels":       map[string]interface{}{"env": "dev"},
+				"not_set":      nil,
+				"settings": []interface{}{
+					map[string]interface{}{"size": json.Number("42"), "tags": []interface{}{"a", "b"}},
+				},
+			},
+		},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddMappings(mappings))
+	require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//foo.googleapis.com/projects/test-project/locations/us-central1/bars/my-bar", assets[0].Name)
+	assert.Equal(t, "foo.googleapis.com/Bar", assets[0].Type)
+	assert.Equal(t, "v1", assets[0].Resource.Version)
+	assert.Equal(t, "Bar", assets[0].Resource.DiscoveryName)
+	assert.Equal(t, map[string]interface{}{
+		"displayName": "My bar",
+		"labels":      map[string]interface{}{"env": "dev"},
+		"config": map[string]interface{}{
+			"size": 42,
+			"tags": []interface{}{"a", "b"},
+		},
+	}, assets[0].Resource.Data)
+	assert.Equal(t, CoverageConverted, c.Coverage()[0].Status)
+}
+
+func TestAddMappings_missingNameField(t *testing.T) {
+	mappings, err := LoadMappings(writeMappings(t, map[string]string{"foo_bar.yaml": testMapping}))
+	require.NoError(t, err)
+	rc := &tfjson.ResourceChange{
+		Address: "google_foo_bar.default",
+		Type:    "google_foo_bar",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After:   map[string]interface{}{"name": "my-bar"},
+		},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddMappings(mappings))
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{rc})
+	assert.Contains(t, err.Error(), "location is not set")
+}
+
+func TestAddMappings_alreadySupported(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	err = c.AddMappings([]*Mapping{{TerraformType: "google_compute_disk", AssetType: "compute.googleapis.com/Disk", Name: "{{name}}"}})
+	assert.Error(t, err)
+}
+
+func TestSupportedTerraformResources(t *testing.T) {
+	list := SupportedTerraformResources([]*Mapping{{TerraformType: "google_foo_bar"}}, []*Plugin{{ResourceTypes: []string{"acme_thing"}}})
+	assert.Contains(t, list, "google_foo_bar")
+	assert.Contains(t, list, "acme_thing")
+	assert.Contains(t, list, "google_compute_disk")
+}
+
+func TestInferSchema(t *testing.T) {
+	got := inferSchema(map[string]interface{}{
+		"name":    "a",
+		"count":   json.Number("1"),
+		"ratio":   json.Number("0.5"),
+		"enabled": true,
+		"labels":  map[string]interface{}{"env": "dev"},
+		"blocks": []interface{}{
+			map[string]interface{}{"a": "x", "b": nil},
+			map[string]interface{}{"b": json.Number("2")},
+		},
+		"nested_map": map[string]interface{}{"k": []interface{}{"v"}},
+	})
+	assert.Equal(t, map[string]*schema.Schema{
+		"name":    {Type: schema.TypeString, Optional: true},
+		"count":   {Type: schema.TypeInt, Optional: true},
+		"ratio":   {Type: schema.TypeFloat, Optional: true},
+		"enabled": {Type: schema.TypeBool, Optional: true},
+		"labels":  {Type: schema.TypeMap, Optional: true, Elem: &schema.Schema{Type: schema.TypeString, Optional: true}},
+		"blocks": {Type: schema.TypeList, Optional: true, Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"a": {Type: schema.TypeString, Optional: true},
+			"b": {Type: schema.TypeInt, Optional: true},
+		}}},
+	}, got)
+}
+
+func TestInferSchema_mixedMap(t *testing.T) {
+	values := map[string]interface{}{
+		"labels": map[string]interface{}{"a": json.Number("1"), "b": "x", "c": true, "d": json.Number("2")},
+		"sizes":  map[string]interface{}{"a": json.Number("1"), "b": nil, "c": json.Number("2")},
+	}
+	// The inferred schema does not depend on the iteration order of maps.
+	for i := 0; i < 20; i++ {
+		got := inferSchema(values)
+		assert.Equal(t, schema.TypeString, got["labels"].Elem.(*schema.Schema).Type)
+		assert.Equal(t, schema.TypeInt, got["sizes"].Elem.(*schema.Schema).Type)
+	}
+}
+
+func TestAddMappings_zeroNameField(t *testing.T) {
+	mapping := `
+terraform_type: google_foo_shard
+asset_type: foo.googleapis.com/Shard
+name: //foo.googleapis.com/projects/{{project}}/shards/{{index}}
+version: v1
+`
+	mappings, err := LoadMappings(writeMappings(t, map[string]string{"foo_shard.yaml": mapping}))
+	require.NoError(t, err)
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddMappings(mappings))
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{{
+		Address: "google_foo_shard.first",
+		Type:    "google_foo_shard",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After:   map[string]interface{}{"index": json.Number("0")},
+		},
+	}})
+	require.NoError(t, err)
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//foo.googleapis.com/projects/test-project/shards/0", assets[0].Name)
+}
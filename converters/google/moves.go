@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+// AddMoves is like AddPlanMoves for the resource changes added with
+// AddResourceChanges.
+func (c *Converter) AddMoves(moves map[string]string) {
+	c.AddPlanMoves("", moves)
+}
+
+// AddPlanMoves records resources that the named plan moves to a new address
+// (key = new address, value = previous address). It must be called before
+// the resource changes of the plan are added. Moved resources are tracked at
+// their new address: a move without any other action is not a change, and a
+// move with an update is converted like any other update. The values of an
+// updated resource before the move are converted as well, into the Prior of
+// its assets, so that violations that existed before the move can be told
+// apart from the ones the update introduces.
+func (c *Converter) AddPlanMoves(plan string, moves map[string]string) {
+	if len(moves) == 0 {
+		return
+	}
+	if c.moves[plan] == nil {
+		c.moves[plan] = make(map[string]string, len(moves))
+	}
+	for address, previous := range moves {
+		c.moves[plan][address] = previous
+	}
+}
+
+// PreviousAddress returns the address a resource was moved from by the named
+// plan, or "" if the resource was not moved.
+func (c *Converter) PreviousAddress(plan, address string) string {
+	return c.moves[plan][address]
+}
+
+// addPrior converts the values of a moved resource before the change into
+// prior assets. Like other assets, prior assets that several resources
+// convert into are merged.
+func (c *Converter) addPrior(rc *tfjson.ResourceChange) error {
+	rd, err := c.newResourceData(rc, rc.Change.Before)
+	if err != nil {
+		return err
+	}
+	for _, mapper := range c.mapperFuncs[rd.Kind()] {
+		convertedItems, err := mapper.Convert(rd, c.cfg)
+		if err != nil {
+			if errors.Cause(err) == converter.ErrNoConversion {
+				continue
+			}
+			return errors.Wrap(err, "converting asset")
+		}
+		for _, converted := range convertedItems {
+			key := converted.Type + converted.Name
+			if existing, exists := c.priors[key]; exists && mapper.MergeCreateUpdate != nil {
+				converted, err = c.merge(mapper.MergeCreateUpdate, existing.converterAsset, converted)
+				if err != nil {
+					return errors.Wrap(err, "merging asset")
+				}
+			}
+			augmented, err := c.augmentAsset(rd, c.cfg, converted)
+			if err != nil {
+				return errors.Wrap(err, "augmenting asset")
+			}
+			augmented, err = c.transform(augmented)
+			if err != nil {
+				return errors.Wrap(err, "transforming asset")
+			}
+			c.priors[key] = augmented
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+)
+
+// V2OrgPolicy is an organization policy of the Organization Policy API v2
+// (orgpolicy.googleapis.com), set on a project, folder or organization.
+type V2OrgPolicy struct {
+	// Name is the name of the policy, e.g.
+	// "projects/my-project/policies/gcp.resourceLocations".
+	Name string           `json:"name"`
+	Spec *V2OrgPolicySpec `json:"spec,omitempty"`
+}
+
+// V2OrgPolicySpec defines the enforcement of a constraint. Rules are kept in
+// order with their conditions: constraints must evaluate them as the
+// Organization Policy service does.
+type V2OrgPolicySpec struct {
+	Etag              string            `json:"etag,omitempty"`
+	Rules             []V2OrgPolicyRule `json:"rules,omitempty"`
+	InheritFromParent bool              `json:"inherit_from_parent,omitempty"`
+	Reset             bool              `json:"reset,omitempty"`
+}
+
+// V2OrgPolicyRule applies when its condition, if any, is met. Exactly one
+// of Values, AllowAll, DenyAll and Enforce is set.
+type V2OrgPolicyRule struct {
+	Values    *V2OrgPolicyValues `json:"values,omitempty"`
+	AllowAll  *bool              `json:"allow_all,omitempty"`
+	DenyAll   *bool              `json:"deny_all,omitempty"`
+	Enforce   *bool              `json:"enforce,omitempty"`
+	Condition *Expr              `json:"condition,omitempty"`
+}
+
+// V2OrgPolicyValues lists the values allowed or denied by a rule of a list
+// constraint.
+type V2OrgPolicyValues struct {
+	AllowedValues []string `json:"allowed_values,omitempty"`
+	DeniedValues  []string `json:"denied_values,omitempty"`
+}
+
+// The conversion library has no v2 organization policies, so they are kept
+// in the org policies of converter.Asset, with a constraint made of
+// v2OrgPolicyPrefix and the JSON encoding of the policy. The merge functions
+// of the library append org policies, and augmentAsset turns them back into
+// v2 organization policies.
+const v2OrgPolicyPrefix = "orgpolicy.googleapis.com/v2:"
+
+func v2OrgPolicyConstraint(p *V2OrgPolicy) string {
+	b, _ := json.Marshal(p)
+	return v2OrgPolicyPrefix + string(b)
+}
+
+// splitV2OrgPolicies separates the org policies keeping v2 organization
+// policies from the other org policies, and returns the v2 policies sorted
+// by name.
+func splitV2OrgPolicies(policies []*converter.OrgPolicy) ([]*converter.OrgPolicy, []*V2OrgPolicy) {
+	var others []*converter.OrgPolicy
+	var v2 []*V2OrgPolicy
+	for _, o := range policies {
+		if p := parseV2OrgPolicy(o); p != nil {
+			v2 = append(v2, p)
+		} else {
+			others = append(others, o)
+		}
+	}
+	sort.Slice(v2, func(i, j int) bool { return v2[i].Name < v2[j].Name })
+	return others, v2
+}
+
+func parseV2OrgPolicy(o *converter.OrgPolicy) *V2OrgPolicy {
+	if o == nil || !strings.HasPrefix(o.Constraint, v2OrgPolicyPrefix) {
+		return nil
+	}
+	p := &V2OrgPolicy{}
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(o.Constraint, v2OrgPolicyPrefix)), p); err != nil {
+		return nil
+	}
+	return p
+}
+
+// v2OrgPolicySchema is the schema of google_org_policy_policy, which is more
+// recent than the provider compiled into the converter. Schemas given to
+// Converter.SetProviderSchemas take precedence.
+var v2OrgPolicySchema = map[string]*schema.Schema{
+	"name":   {Type: schema.TypeString, Required: true},
+	"parent": {Type: schema.TypeString, Required: true},
+	"spec": {
+		Type:     schema.TypeList,
+		Optional: true,
+		MaxItems: 1,
+		Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+			"etag":                {Type: schema.TypeString, Computed: true},
+			"inherit_from_parent": {Type: schema.TypeBool, Optional: true},
+			"reset":               {Type: schema.TypeBool, Optional: true},
+			"update_time":         {Type: schema.TypeString, Computed: true},
+			"rules": {
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+					"allow_all": {Type: schema.TypeString, Optional: true},
+					"deny_all":  {Type: schema.TypeString, Optional: true},
+					"enforce":   {Type: schema.TypeString, Optional: true},
+					"condition": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 1,
+						Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+							"description": {Type: schema.TypeString, Optional: true},
+							"expression":  {Type: schema.TypeString, Optional: true},
+							"location":    {Type: schema.TypeString, Optional: true},
+							"title":       {Type: schema.TypeString, Optional: true},
+						}},
+					},
+					"values": {
+						Type:     schema.TypeList,
+						Optional: true,
+						MaxItems: 1,
+						Elem: &schema.Resource{Schema: map[string]*schema.Schema{
+							"allowed_values": {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+							"denied_values":  {Type: schema.TypeList, Optional: true, Elem: &schema.Schema{Type: schema.TypeString}},
+						}},
+					},
+				}},
+			},
+		}},
+	},
+}
+
+// builtinSchemas are the schemas of the resource types converted by this
+// package that the compiled provider does not know.
+var builtinSchemas = map[string]map[string]*schema.Schema{
+	"google_org_policy_policy": v2OrgPolicySchema,
+}
+
+// v2OrgPolicyParents maps the parents of v2 organization policies to the
+// types of the assets they are set on.
+var v2OrgPolicyParents = map[string]string{
+	"projects":      "cloudresourcemanager.googleapis.com/Project",
+	"folders":       "cloudresourcemanager.googleapis.com/Folder",
+	"organizations": "cloudresourcemanager.googleapis.com/Organization",
+}
+
+// v2OrgPolicyMappers returns the mappers of google_org_policy_policy. A
+// policy replaces the policy with the same name on its parent. Policies are
+// not fetched, so deleting a policy only removes it from a parent converted
+// from an earlier plan (deletions of a plan are added before its creates and
+// updates).
+func v2OrgPolicyMappers() map[string][]converter.Mapper {
+	return map[string][]converter.Mapper{
+		"google_org_policy_policy": {{
+			Convert:           convertV2OrgPolicy,
+			MergeCreateUpdate: mergeV2OrgPolicy,
+			MergeDelete:       mergeDeleteV2OrgPolicy,
+		}},
+	}
+}
+
+func convertV2OrgPolicy(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	parent, ok := d.GetOk("parent")
+	if !ok {
+		return nil, fmt.Errorf("required field 'parent' is not set")
+	}
+	assetType, ok := v2OrgPolicyParents[strings.SplitN(parent.(string), "/", 2)[0]]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parent %q", parent)
+	}
+	name, ok := d.GetOk("name")
+	if !ok {
+		return nil, fmt.Errorf("required field 'name' is not set")
+	}
+	// The name is either the constraint or the full name of the policy.
+	if !strings.Contains(name.(string), "/policies/") {
+		name = parent.(string) + "/policies/" + name.(string)
+	}
+
+	policy := &V2OrgPolicy{Name: name.(string)}
+	if _, ok := d.GetOk("spec"); ok {
+		policy.Spec = &V2OrgPolicySpec{
+			Etag:              stringValue(d.Get("spec.0.etag")),
+			InheritFromParent: d.Get("spec.0.inherit_from_parent").(bool),
+			Reset:             d.Get("spec.0.reset").(bool),
+		}
+		rules, _ := d.Get("spec.0.rules").([]interface{})
+		for i := range rules {
+			rule, err := expandV2OrgPolicyRule(d, fmt.Sprintf("spec.0.rules.%d.", i))
+			if err != nil {
+				return nil, err
+			}
+			policy.Spec.Rules = append(policy.Spec.Rules, rule)
+		}
+	}
+
+	return []converter.Asset{{
+		Name: "//cloudresourcemanager.googleapis.com/" + parent.(string),
+		Type: assetType,
+		OrgPolicy: []*converter.OrgPolicy{{
+			Constraint: v2OrgPolicyConstraint(policy),
+		}},
+	}}, nil
+}
+
+func expandV2OrgPolicyRule(d converter.TerraformResourceData, prefix string) (V2OrgPolicyRule, error) {
+	var rule V2OrgPolicyRule
+	for field, dst := range map[string]**bool{
+		"allow_all": &rule.AllowAll,
+		"deny_all":  &rule.DenyAll,
+		"enforce":   &rule.Enforce,
+	} {
+		v, err := expandV2OrgPolicyBool(stringValue(d.Get(prefix + field)))
+		if err != nil {
+			return rule, fmt.Errorf("%s%s: %w", prefix, field, err)
+		}
+		*dst = v
+	}
+	if _, ok := d.GetOk(prefix + "values"); ok {
+		rule.Values = &V2OrgPolicyValues{
+			AllowedValues: stringList(d.Get(prefix + "values.0.allowed_values")),
+			DeniedValues:  stringList(d.Get(prefix + "values.0.denied_values")),
+		}
+	}
+	if expression, ok := d.GetOk(prefix + "condition.0.expression"); ok {
+		rule.Condition = &Expr{
+			Expression:  stringValue(expression),
+			Title:       stringValue(d.Get(prefix + "condition.0.title")),
+			Description: stringValue(d.Get(prefix + "condition.0.description")),
+			Location:    stringValue(d.Get(prefix + "condition.0.location")),
+		}
+	}
+	return rule, nil
+}
+
+// expandV2OrgPolicyBool reads the "TRUE" and "FALSE" values of the
+// allow_all, deny_all and enforce attributes. Unset attributes return nil.
+func expandV2OrgPolicyBool(v string) (*bool, error) {
+	var b bool
+	switch strings.ToUpper(v) {
+	case "":
+		return nil, nil
+	case "TRUE":
+		b = true
+	case "FALSE":
+		b = false
+	default:
+		return nil, fmt.Errorf("expected TRUE or FALSE, got %q", v)
+	}
+	return &b, nil
+}
+
+func stringList(v interface{}) []string {
+	list, _ := v.([]interface{})
+	var result []string
+	for _, e := range list {
+		if s, ok := e.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// v2OrgPolicyNames returns the names of the v2 organization policies kept in
+// the org policies of an asset.
+func v2OrgPolicyNames(asset converter.Asset) map[string]bool {
+	names := make(map[string]bool)
+	for _, o := range asset.OrgPolicy {
+		if p := parseV2OrgPolicy(o); p != nil {
+			names[p.Name] = true
+		}
+	}
+	return names
+}
+
+// withoutV2OrgPolicies returns the org policies without the v2 organization
+// policies with the given names.
+func withoutV2OrgPolicies(policies []*converter.OrgPolicy, names map[string]bool) []*converter.OrgPolicy {
+	var result []*converter.OrgPolicy
+	for _, o := range policies {
+		if p := parseV2OrgPolicy(o); p != nil && names[p.Name] {
+			continue
+		}
+		result = append(result, o)
+	}
+	return result
+}
+
+// mergeV2OrgPolicy replaces the v2 organization policies of incoming in
+// existing.
+func mergeV2OrgPolicy(existing, incoming converter.Asset) converter.Asset {
+	existing.OrgPolicy = append(withoutV2OrgPolicies(existing.OrgPolicy, v2OrgPolicyNames(incoming)), incoming.OrgPolicy...)
+	return existing
+}
+
+// mergeDeleteV2OrgPolicy removes the v2 organization policies of incoming
+// from existing.
+func mergeDeleteV2OrgPolicy(existing, incoming converter.Asset) converter.Asset {
+	existing.OrgPolicy = withoutV2OrgPolicies(existing.OrgPolicy, v2OrgPolicyNames(incoming))
+	return existing
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddResourceChanges_v2OrgPolicies(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("google_org_policy_policy", "locations", create, map[string]interface{}{
+			"name":   "gcp.resourceLocations",
+			"parent": "projects/" + testProject,
+			"spec": []interface{}{map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{
+						"values": []interface{}{map[string]interface{}{
+							"allowed_values": []interface{}{"in:us-locations"},
+						}},
+						"condition": []interface{}{map[string]interface{}{
+							"title":      "dev",
+							"expression": `resource.matchTag("123/env", "dev")`,
+						}},
+					},
+					map[string]interface{}{"deny_all": "TRUE"},
+				},
+			}},
+		}),
+		newTestResourceChange("google_org_policy_policy", "keys", create, map[string]interface{}{
+			"name":   "projects/" + testProject + "/policies/iam.disableServiceAccountKeyCreation",
+			"parent": "projects/" + testProject,
+			"spec": []interface{}{map[string]interface{}{
+				"rules": []interface{}{map[string]interface{}{"enforce": "FALSE"}},
+			}},
+		}),
+		newTestResourceChange("google_org_policy_policy", "inherit", create, map[string]interface{}{
+			"name":   "compute.vmExternalIpAccess",
+			"parent": "projects/" + testProject,
+			"spec": []interface{}{map[string]interface{}{
+				"inherit_from_parent": true,
+				"reset":               false,
+			}},
+		}),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//cloudresourcemanager.googleapis.com/projects/"+testProject, assets[0].Name)
+	assert.Equal(t, "cloudresourcemanager.googleapis.com/Project", assets[0].Type)
+	assert.Nil(t, assets[0].OrgPolicy)
+	yes, no := true, false
+	assert.Equal(t, []*V2OrgPolicy{
+		{
+			Name: "projects/" + testProject + "/policies/compute.vmExternalIpAccess",
+			Spec: &V2OrgPolicySpec{InheritFromParent: true},
+		},
+		{
+			Name: "projects/" + testProject + "/policies/gcp.resourceLocations",
+			Spec: &V2OrgPolicySpec{Rules: []V2OrgPolicyRule{
+				{
+					Values:    &V2OrgPolicyValues{AllowedValues: []string{"in:us-locations"}},
+					Condition: &Expr{Title: "dev", Expression: `resource.matchTag("123/env", "dev")`},
+				},
+				{DenyAll: &yes},
+			}},
+		},
+		{
+			Name: "projects/" + testProject + "/policies/iam.disableServiceAccountKeyCreation",
+			Spec: &V2OrgPolicySpec{Rules: []V2OrgPolicyRule{{Enforce: &no}}},
+		},
+	}, assets[0].V2OrgPolicies)
+	assert.Equal(t, []string{`v2_org_policies["projects/test-project/policies/gcp.resourceLocations"]`}, assets[0].Provenance[0].Fields)
+
+	// A policy replaces the policy with the same name, and deleting it
+	// removes it.
+	mapper := c.mapperFuncs["google_org_policy_policy"][0]
+	update := newTestResourceChange("google_org_policy_policy", "inherit", tfjson.Actions{"update"}, map[string]interface{}{
+		"name":   "compute.vmExternalIpAccess",
+		"parent": "projects/" + testProject,
+		"spec":   []interface{}{map[string]interface{}{"reset": true}},
+	})
+	rd, err := c.newResourceData(update, update.Change.After)
+	require.NoError(t, err)
+	converted, err := mapper.Convert(rd, c.cfg)
+	require.NoError(t, err)
+	merged := mapper.MergeCreateUpdate(assets[0].converterAsset, converted[0])
+	augmented, err := c.augmentAsset(rd, c.cfg, merged)
+	require.NoError(t, err)
+	require.Len(t, augmented.V2OrgPolicies, 3)
+	assert.Equal(t, &V2OrgPolicySpec{Reset: true}, augmented.V2OrgPolicies[0].Spec)
+
+	merged = mapper.MergeDelete(merged, converted[0])
+	augmented, err = c.augmentAsset(rd, c.cfg, merged)
+	require.NoError(t, err)
+	require.Len(t, augmented.V2OrgPolicies, 2)
+	assert.Equal(t, "projects/"+testProject+"/policies/gcp.resourceLocations", augmented.V2OrgPolicies[0].Name)
+}
+
+func TestAddResourceChanges_v2OrgPolicyInvalid(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"BadParent": {"name": "gcp.resourceLocations", "parent": "billingAccounts/123"},
+		"BadEnforce": {
+			"name":   "gcp.resourceLocations",
+			"parent": "organizations/123",
+			"spec":   []interface{}{map[string]interface{}{"rules": []interface{}{map[string]interface{}{"enforce": "maybe"}}}},
+		},
+	}
+	for name, values := range cases {
+		t.Run(name, func(t *testing.T) {
+			c, err := newTestConverter()
+			require.NoError(t, err)
+			rc := &tfjson.ResourceChange{
+				Address: "google_org_policy_policy.p",
+				Type:    "google_org_policy_policy",
+				Name:    "p",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{"create"}, After: values},
+			}
+			assert.Error(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+		})
+	}
+}
+
+func TestAddPlanResourceChanges_v2OrgPolicyDeletion(t *testing.T) {
+	policy := func(name string, actions tfjson.Actions) *tfjson.ResourceChange {
+		return newTestResourceChange("google_org_policy_policy", name, actions, map[string]interface{}{
+			"name":   name,
+			"parent": "projects/" + testProject,
+			"spec":   []interface{}{map[string]interface{}{"inherit_from_parent": true}},
+		})
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	create := tfjson.Actions{"create"}
+	require.NoError(t, c.AddPlanResourceChanges("a.json", []*tfjson.ResourceChange{
+		policy("compute.vmExternalIpAccess", create),
+		policy("gcp.resourceLocations", create),
+	}))
+	// A plan creating a policy and deleting another one on the same parent.
+	require.NoError(t, c.AddPlanResourceChanges("b.json", []*tfjson.ResourceChange{
+		policy("iam.disableServiceAccountKeyCreation", create),
+		policy("gcp.resourceLocations", tfjson.Actions{"delete"}),
+	}))
+
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	var names []string
+	for _, p := range assets[0].V2OrgPolicies {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{
+		"projects/" + testProject + "/policies/compute.vmExternalIpAccess",
+		"projects/" + testProject + "/policies/iam.disableServiceAccountKeyCreation",
+	}, names)
+	require.Len(t, assets[0].Provenance, 4)
+	assert.Equal(t, []string{`v2_org_policies["projects/test-project/policies/gcp.resourceLocations"]`}, assets[0].Provenance[2].Removed)
+
+	coverage := c.Coverage()
+	require.Len(t, coverage, 4)
+	for _, cov := range coverage {
+		assert.Equal(t, CoverageConverted, cov.Status, cov.Address)
+	}
+}
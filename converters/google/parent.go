@@ -0,0 +1,172 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"fmt"
+	"strings"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+)
+
+const (
+	projectAssetType      = "cloudresourcemanager.googleapis.com/Project"
+	folderAssetType       = "cloudresourcemanager.googleapis.com/Folder"
+	organizationAssetType = "cloudresourcemanager.googleapis.com/Organization"
+)
+
+// containerServices maps the collections of the resource hierarchy, as they
+// appear in asset names, to the service of their assets.
+var containerServices = map[string]string{
+	"projects":        "cloudresourcemanager.googleapis.com",
+	"folders":         "cloudresourcemanager.googleapis.com",
+	"organizations":   "cloudresourcemanager.googleapis.com",
+	"billingAccounts": "cloudbilling.googleapis.com",
+}
+
+// ancestryCollections maps the node types of ancestry paths to the
+// collections of the resource hierarchy.
+var ancestryCollections = map[string]string{
+	"project":      "projects",
+	"folder":       "folders",
+	"organization": "organizations",
+}
+
+// assetParent returns the full name of the parent of an asset's resource, the
+// way CAI reports it:
+//   - a project's parent is its folder or organization, from its ancestry,
+//   - a folder's parent is its parent folder or organization,
+//   - an organization has no parent,
+//   - a resource of an organization, folder or billing account (e.g. an
+//     organization sink) has that organization, folder or billing account as
+//     parent,
+//   - any other resource has its project as parent.
+func assetParent(tfData converter.TerraformResourceData, cai converter.Asset, project, ancestry string) string {
+	switch cai.Type {
+	case projectAssetType:
+		return ancestryParent(ancestry)
+	case folderAssetType:
+		if parent, ok := tfData.GetOk("parent"); ok {
+			if s, ok := parent.(string); ok && s != "" {
+				return "//cloudresourcemanager.googleapis.com/" + s
+			}
+		}
+		return ""
+	case organizationAssetType:
+		return ""
+	}
+	if collection, id, ok := nameContainer(cai.Name); ok && collection != "projects" {
+		return fmt.Sprintf("//%s/%s/%s", containerServices[collection], collection, id)
+	}
+	return fmt.Sprintf("//cloudresourcemanager.googleapis.com/projects/%v", project)
+}
+
+// ancestryParent returns the full name of the parent of the last node of an
+// ancestry path (e.g. "organization/123/folder/456/project/foo"), or "" if
+// it is not known.
+func ancestryParent(ancestry string) string {
+	nodes := strings.Split(ancestry, "/")
+	if len(nodes) < 4 || len(nodes)%2 != 0 {
+		return ""
+	}
+	collection, ok := ancestryCollections[nodes[len(nodes)-4]]
+	id := nodes[len(nodes)-3]
+	if !ok || id == "" || id == "unknown" {
+		return ""
+	}
+	return fmt.Sprintf("//cloudresourcemanager.googleapis.com/%s/%s", collection, id)
+}
+
+// nameContainer returns the collection and id of the outermost project,
+// folder, organization or billing account in an asset name (e.g.
+// "organizations", "123" for "//logging.googleapis.com/organizations/123/sinks/s").
+func nameContainer(name string) (collection, id string, ok bool) {
+	segments := strings.Split(strings.TrimPrefix(name, "//"), "/")
+	// Skip the service.
+	for i := 1; i+1 < len(segments); i++ {
+		if _, ok := containerServices[segments[i]]; ok && segments[i+1] != "" {
+			return segments[i], segments[i+1], true
+		}
+	}
+	return "", "", false
+}
+
+// assetAncestors returns the resource hierarchy of an asset, from its closest
+// ancestor to the organization (e.g. "projects/foo", "folders/456",
+// "organizations/123"), like the ancestors of CAI exports. They are read from
+// the ancestry path, except for folders and organizations, whose ancestors
+// start with themselves. Nodes of the ancestry path with an unknown id are
+// left out.
+//
+// The ancestry manager only resolves the ancestry of projects, so the
+// ancestors of a folder are the folder and its parent, followed by the
+// ancestors of the parent in the ancestry path of the project of the
+// conversion (e.g. from --ancestry), if the parent is in it.
+//
+// Unlike CAI exports, which name projects by number, projects are named by
+// the id of the ancestry path, which is the project id unless the number is
+// known (e.g. for an existing google_project).
+func assetAncestors(tfData converter.TerraformResourceData, cai converter.Asset, ancestry string) []string {
+	pathAncestors := ancestryAncestors(ancestry)
+	switch cai.Type {
+	case folderAssetType:
+		self := strings.TrimPrefix(cai.Name, "//cloudresourcemanager.googleapis.com/")
+		if tail := ancestorsFrom(pathAncestors, self); tail != nil {
+			return tail
+		}
+		ancestors := []string{self}
+		if parent, ok := tfData.GetOk("parent"); ok {
+			if s, ok := parent.(string); ok && s != "" {
+				if tail := ancestorsFrom(pathAncestors, s); tail != nil {
+					return append(ancestors, tail...)
+				}
+				ancestors = append(ancestors, s)
+			}
+		}
+		return ancestors
+	case organizationAssetType:
+		return []string{strings.TrimPrefix(cai.Name, "//cloudresourcemanager.googleapis.com/")}
+	}
+	return pathAncestors
+}
+
+// ancestryAncestors returns the nodes of an ancestry path with a known id,
+// from the last one to the organization.
+func ancestryAncestors(ancestry string) []string {
+	nodes := strings.Split(ancestry, "/")
+	if len(nodes)%2 != 0 {
+		return nil
+	}
+	var ancestors []string
+	for i := len(nodes) - 2; i >= 0; i -= 2 {
+		collection, ok := ancestryCollections[nodes[i]]
+		if !ok || nodes[i+1] == "" || nodes[i+1] == "unknown" {
+			continue
+		}
+		ancestors = append(ancestors, collection+"/"+nodes[i+1])
+	}
+	return ancestors
+}
+
+// ancestorsFrom returns the ancestors starting with the given one, or nil if
+// it is not one of them.
+func ancestorsFrom(ancestors []string, ancestor string) []string {
+	for i, a := range ancestors {
+		if a == ancestor {
+			return ancestors[i:]
+		}
+	}
+	return nil
+}
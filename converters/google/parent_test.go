@@ -0,0 +1,200 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetParent(t *testing.T) {
+	cases := []struct {
+		name      string
+		kind      string
+		values    map[string]interface{}
+		assetName string
+		assetType string
+		ancestry  string
+		want      string
+	}{
+		{
+			name:      "ProjectInFolder",
+			kind:      "google_project",
+			values:    map[string]interface{}{"project_id": "foo", "folder_id": "456"},
+			assetName: "//cloudresourcemanager.googleapis.com/projects/foo",
+			assetType: projectAssetType,
+			ancestry:  "organization/123/folder/456/project/foo",
+			want:      "//cloudresourcemanager.googleapis.com/folders/456",
+		},
+		{
+			name:      "ProjectInOrganization",
+			kind:      "google_project",
+			values:    map[string]interface{}{"project_id": "foo", "org_id": "123"},
+			assetName: "//cloudresourcemanager.googleapis.com/projects/foo",
+			assetType: projectAssetType,
+			ancestry:  "organization/123/project/foo",
+			want:      "//cloudresourcemanager.googleapis.com/organizations/123",
+		},
+		{
+			name:      "ProjectUnknownFolder",
+			kind:      "google_project",
+			values:    map[string]interface{}{"project_id": "foo"},
+			assetName: "//cloudresourcemanager.googleapis.com/projects/foo",
+			assetType: projectAssetType,
+			ancestry:  "project/foo",
+			want:      "",
+		},
+		{
+			name:      "Folder",
+			kind:      "google_folder",
+			values:    map[string]interface{}{"display_name": "f", "parent": "folders/456"},
+			assetName: "//cloudresourcemanager.googleapis.com/folders/789",
+			assetType: folderAssetType,
+			want:      "//cloudresourcemanager.googleapis.com/folders/456",
+		},
+		{
+			name:      "OrganizationSink",
+			kind:      "google_logging_organization_sink",
+			values:    map[string]interface{}{"name": "s", "org_id": "123"},
+			assetName: "//logging.googleapis.com/organizations/123/sinks/s",
+			assetType: "logging.googleapis.com/LogSink",
+			want:      "//cloudresourcemanager.googleapis.com/organizations/123",
+		},
+		{
+			name:      "BillingAccountSink",
+			kind:      "google_logging_billing_account_sink",
+			values:    map[string]interface{}{"name": "s", "billing_account": "ABC"},
+			assetName: "//logging.googleapis.com/billingAccounts/ABC/sinks/s",
+			assetType: "logging.googleapis.com/LogSink",
+			want:      "//cloudbilling.googleapis.com/billingAccounts/ABC",
+		},
+		{
+			name:      "ProjectResource",
+			kind:      "google_storage_bucket",
+			values:    map[string]interface{}{"name": "b"},
+			assetName: "//storage.googleapis.com/b",
+			assetType: "storage.googleapis.com/Bucket",
+			want:      "//cloudresourcemanager.googleapis.com/projects/" + testProject,
+		},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := &tfjson.ResourceChange{
+				Type:   tc.kind,
+				Change: &tfjson.Change{Actions: tfjson.Actions{"create"}, After: tc.values},
+			}
+			rd, err := c.newResourceData(rc, tc.values)
+			require.NoError(t, err)
+			cai := converter.Asset{Name: tc.assetName, Type: tc.assetType}
+			assert.Equal(t, tc.want, assetParent(rd, cai, testProject, tc.ancestry))
+		})
+	}
+}
+
+func TestAssetAncestors(t *testing.T) {
+	cases := []struct {
+		name      string
+		kind      string
+		values    map[string]interface{}
+		assetName string
+		assetType string
+		ancestry  string
+		want      []string
+	}{
+		{
+			name:      "ProjectResource",
+			kind:      "google_storage_bucket",
+			values:    map[string]interface{}{"name": "b"},
+			assetName: "//storage.googleapis.com/b",
+			assetType: "storage.googleapis.com/Bucket",
+			ancestry:  "organization/123/folder/456/project/foo",
+			want:      []string{"projects/foo", "folders/456", "organizations/123"},
+		},
+		{
+			name:      "UnknownOrganization",
+			kind:      "google_project",
+			values:    map[string]interface{}{"project_id": "foo", "folder_id": "456"},
+			assetName: "//cloudresourcemanager.googleapis.com/projects/foo",
+			assetType: projectAssetType,
+			ancestry:  "organization/unknown/folder/456/project/foo",
+			want:      []string{"projects/foo", "folders/456"},
+		},
+		{
+			name:      "Folder",
+			kind:      "google_folder",
+			values:    map[string]interface{}{"display_name": "f", "parent": "organizations/123"},
+			assetName: "//cloudresourcemanager.googleapis.com/folders/789",
+			assetType: folderAssetType,
+			ancestry:  "organization/123/project/foo",
+			want:      []string{"folders/789", "organizations/123"},
+		},
+		{
+			name:      "NestedFolder",
+			kind:      "google_folder",
+			values:    map[string]interface{}{"display_name": "f", "parent": "folders/456"},
+			assetName: "//cloudresourcemanager.googleapis.com/folders/789",
+			assetType: folderAssetType,
+			ancestry:  "organization/123/folder/345/folder/456/project/foo",
+			want:      []string{"folders/789", "folders/456", "folders/345", "organizations/123"},
+		},
+		{
+			name:      "FolderOutsideAncestry",
+			kind:      "google_folder",
+			values:    map[string]interface{}{"display_name": "f", "parent": "folders/999"},
+			assetName: "//cloudresourcemanager.googleapis.com/folders/789",
+			assetType: folderAssetType,
+			ancestry:  "organization/123/folder/456/project/foo",
+			want:      []string{"folders/789", "folders/999"},
+		},
+		{
+			name:      "FolderInAncestry",
+			kind:      "google_folder_iam_member",
+			values:    map[string]interface{}{"folder": "folders/456", "role": "roles/viewer", "member": "user:jane@example.com"},
+			assetName: "//cloudresourcemanager.googleapis.com/folders/456",
+			assetType: folderAssetType,
+			ancestry:  "organization/123/folder/345/folder/456/project/foo",
+			want:      []string{"folders/456", "folders/345", "organizations/123"},
+		},
+		{
+			name:      "Organization",
+			kind:      "google_organization_iam_member",
+			values:    map[string]interface{}{"org_id": "123", "role": "roles/viewer", "member": "user:jane@example.com"},
+			assetName: "//cloudresourcemanager.googleapis.com/organizations/123",
+			assetType: organizationAssetType,
+			ancestry:  "organization/123/project/foo",
+			want:      []string{"organizations/123"},
+		},
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc := &tfjson.ResourceChange{
+				Type:   tc.kind,
+				Change: &tfjson.Change{Actions: tfjson.Actions{"create"}, After: tc.values},
+			}
+			rd, err := c.newResourceData(rc, tc.values)
+			require.NoError(t, err)
+			cai := converter.Asset{Name: tc.assetName, Type: tc.assetType}
+			assert.Equal(t, tc.want, assetAncestors(rd, cai, tc.ancestry))
+		})
+	}
+}
@@ -0,0 +1,396 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/pkg/errors"
+)
+
+// PluginPrefix is the prefix of the file names of converter plugins, e.g.
+// "terraform-validator-converter-acme".
+const PluginPrefix = "terraform-validator-converter-"
+
+// PluginProtocolVersion is the version of the plugin protocol implemented by
+// this package.
+const PluginProtocolVersion = 1
+
+// DefaultPluginTimeout is the time a plugin operation may run for if the
+// plugin has no Timeout.
+const DefaultPluginTimeout = time.Minute
+
+// Plugin operations. A plugin is run once per operation: it reads a
+// PluginRequest from stdin and writes a PluginResponse to stdout.
+const (
+	// PluginDescribe asks for the resource types and operations the plugin
+	// supports.
+	PluginDescribe = "describe"
+	// PluginConvert converts a resource into assets.
+	PluginConvert = "convert"
+	// PluginFetch fetches the current asset of a resource from the API.
+	PluginFetch = "fetch"
+	// PluginMergeCreateUpdate merges an asset created or updated by a
+	// resource into an existing asset.
+	PluginMergeCreateUpdate = "merge_create_update"
+	// PluginMergeDelete removes an asset deleted by a resource from an
+	// existing asset.
+	PluginMergeDelete = "merge_delete"
+)
+
+// PluginRequest is the message sent to a plugin on stdin.
+type PluginRequest struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	Operation       string `json:"operation"`
+	// ResourceType, Before, After and Config are set for convert and
+	// fetch. After is null for deletions.
+	ResourceType string                 `json:"resource_type,omitempty"`
+	Before       map[string]interface{} `json:"before,omitempty"`
+	After        map[string]interface{} `json:"after,omitempty"`
+	Config       *PluginConfig          `json:"config,omitempty"`
+	// Existing and Incoming are set for merges.
+	Existing *PluginAsset `json:"existing,omitempty"`
+	Incoming *PluginAsset `json:"incoming,omitempty"`
+}
+
+// PluginAsset is an asset in the terraform-google-conversion format, with
+// the conditions of IAM bindings and the audit configs of IAM policies,
+// which that format has no fields for, and the v2 organization policies set
+// on the asset in V2OrgPolicies rather than in OrgPolicy.
+type PluginAsset struct {
+	Name          string                   `json:"name"`
+	Type          string                   `json:"asset_type"`
+	Resource      *converter.AssetResource `json:"resource,omitempty"`
+	IAMPolicy     *IAMPolicy               `json:"iam_policy,omitempty"`
+	OrgPolicy     []*converter.OrgPolicy   `json:"org_policy,omitempty"`
+	V2OrgPolicies []*V2OrgPolicy           `json:"v2_org_policies,omitempty"`
+}
+
+// newPluginAsset returns the plugin asset of a converter.Asset, decoding the
+// conditions, audit configs and policies the conversion library has no
+// fields for (see conditionalRole, auditLogConfigMember and
+// v2OrgPolicyPrefix).
+func newPluginAsset(a converter.Asset) (*PluginAsset, error) {
+	var policy *IAMPolicy
+	if a.IAMPolicy != nil {
+		others, auditConfigs := splitAuditConfigs(a.IAMPolicy.Bindings)
+		bindings, err := splitConditionalBindings(others)
+		if err != nil {
+			return nil, fmt.Errorf("getting IAM bindings of %v: %w", a.Name, err)
+		}
+		policy = &IAMPolicy{Bindings: bindings, AuditConfigs: auditConfigs}
+	}
+	orgPolicies, v2OrgPolicies := splitV2OrgPolicies(a.OrgPolicy)
+	return &PluginAsset{
+		Name:          a.Name,
+		Type:          a.Type,
+		Resource:      a.Resource,
+		IAMPolicy:     policy,
+		OrgPolicy:     orgPolicies,
+		V2OrgPolicies: v2OrgPolicies,
+	}, nil
+}
+
+// converterAsset returns the converter.Asset of a plugin asset.
+func (a *PluginAsset) converterAsset() converter.Asset {
+	orgPolicies := a.OrgPolicy
+	for _, p := range a.V2OrgPolicies {
+		orgPolicies = append(orgPolicies, &converter.OrgPolicy{Constraint: v2OrgPolicyConstraint(p)})
+	}
+	var policy *converter.IAMPolicy
+	if a.IAMPolicy != nil {
+		bindings := conditionalBindings(a.IAMPolicy.Bindings)
+		policy = &converter.IAMPolicy{Bindings: append(bindings, auditConfigBindings(a.IAMPolicy.AuditConfigs)...)}
+	}
+	return converter.Asset{
+		Name:      a.Name,
+		Type:      a.Type,
+		Resource:  a.Resource,
+		IAMPolicy: policy,
+		OrgPolicy: orgPolicies,
+	}
+}
+
+// PluginConfig is the provider configuration sent to plugins.
+type PluginConfig struct {
+	Project string `json:"project,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Zone    string `json:"zone,omitempty"`
+}
+
+// PluginResponse is the message read from a plugin on stdout.
+type PluginResponse struct {
+	// Set for describe.
+	ProtocolVersion   int      `json:"protocol_version,omitempty"`
+	ResourceTypes     []string `json:"resource_types,omitempty"`
+	Fetch             bool     `json:"fetch,omitempty"`
+	MergeCreateUpdate bool     `json:"merge_create_update,omitempty"`
+	MergeDelete       bool     `json:"merge_delete,omitempty"`
+
+	// Assets are the converted assets, the fetched asset or the merged
+	// asset.
+	Assets []PluginAsset `json:"assets,omitempty"`
+	// NoConversion reports that the resource does not convert into any
+	// asset, see converter.ErrNoConversion.
+	NoConversion bool `json:"no_conversion,omitempty"`
+	// EmptyIdentityField reports that the asset cannot be fetched because
+	// its identity is not known yet, see converter.ErrEmptyIdentityField.
+	EmptyIdentityField bool `json:"empty_identity_field,omitempty"`
+	// Error fails the operation.
+	Error string `json:"error,omitempty"`
+}
+
+// Plugin is an executable that converts resource types over the plugin
+// protocol, similar to Terraform's own providers.
+type Plugin struct {
+	Path              string
+	ResourceTypes     []string
+	Fetch             bool
+	MergeCreateUpdate bool
+	MergeDelete       bool
+	// Timeout is the time one operation may run for before the plugin is
+	// killed, DefaultPluginTimeout if zero.
+	Timeout time.Duration
+}
+
+// PluginError is an error running a plugin operation.
+type PluginError struct {
+	Plugin    string
+	Operation string
+	Err       error
+}
+
+func (e *PluginError) Error() string {
+	return fmt.Sprintf("plugin %s: %s: %v", filepath.Base(e.Plugin), e.Operation, e.Err)
+}
+
+func (e *PluginError) Unwrap() error {
+	return e.Err
+}
+
+// LoadPlugins discovers the executables named PluginPrefix* in dir and asks
+// each of them which resource types it converts. Each plugin operation may
+// run for the given timeout, or DefaultPluginTimeout if it is zero.
+func LoadPlugins(dir string, timeout time.Duration) ([]*Plugin, error) {
+	files, err := filepath.Glob(filepath.Join(dir, PluginPrefix+"*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var plugins []*Plugin
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening plugin")
+		}
+		if !info.Mode().IsRegular() || info.Mode()&0111 == 0 {
+			continue
+		}
+		p := &Plugin{Path: f, Timeout: timeout}
+		resp, err := p.call(&PluginRequest{Operation: PluginDescribe})
+		if err != nil {
+			return nil, err
+		}
+		if resp.ProtocolVersion != PluginProtocolVersion {
+			return nil, &PluginError{Plugin: f, Operation: PluginDescribe, Err: fmt.Errorf("unsupported protocol version %d, want %d", resp.ProtocolVersion, PluginProtocolVersion)}
+		}
+		p.ResourceTypes = resp.ResourceTypes
+		p.Fetch = resp.Fetch
+		p.MergeCreateUpdate = resp.MergeCreateUpdate
+		p.MergeDelete = resp.MergeDelete
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// AddPlugins registers the conversion of the resource types of the given
+// plugins next to the built-in mappers. Plugins that support fetch and merge
+// take part in merging assets like the built-in mappers. Resource types
+// that are not in the provider schema are read with a schema inferred from
+// their values.
+func (c *Converter) AddPlugins(plugins []*Plugin) error {
+	for _, p := range plugins {
+		for _, kind := range p.ResourceTypes {
+			if _, ok := c.mapperFuncs[kind]; ok {
+				return fmt.Errorf("plugin %s: resource type %s is already supported", p.Path, kind)
+			}
+		}
+	}
+	for _, p := range plugins {
+		for _, kind := range p.ResourceTypes {
+			c.mapperFuncs[kind] = []converter.Mapper{p.mapper(&c.mergeErr)}
+		}
+	}
+	return nil
+}
+
+// mapper returns the mapper of the plugin's resource types. The errors of its
+// merge functions are stored into mergeErr, see mergeFunc.
+func (p *Plugin) mapper(mergeErr *error) converter.Mapper {
+	m := converter.Mapper{Convert: p.convert}
+	if p.Fetch {
+		m.Fetch = p.fetch
+	}
+	if p.MergeCreateUpdate {
+		m.MergeCreateUpdate = p.mergeFunc(PluginMergeCreateUpdate, mergeErr)
+	}
+	if p.MergeDelete {
+		m.MergeDelete = p.mergeFunc(PluginMergeDelete, mergeErr)
+	}
+	return m
+}
+
+func (p *Plugin) convert(d converter.TerraformResourceData, config *converter.Config) ([]converter.Asset, error) {
+	resp, err := p.call(p.resourceRequest(PluginConvert, d, config))
+	if err != nil {
+		return nil, err
+	}
+	if resp.NoConversion {
+		return nil, converter.ErrNoConversion
+	}
+	assets := make([]converter.Asset, 0, len(resp.Assets))
+	for i := range resp.Assets {
+		assets = append(assets, resp.Assets[i].converterAsset())
+	}
+	return assets, nil
+}
+
+func (p *Plugin) fetch(d converter.TerraformResourceData, config *converter.Config) (converter.Asset, error) {
+	resp, err := p.call(p.resourceRequest(PluginFetch, d, config))
+	if err != nil {
+		return converter.Asset{}, err
+	}
+	if resp.EmptyIdentityField {
+		return converter.Asset{}, converter.ErrEmptyIdentityField
+	}
+	if len(resp.Assets) != 1 {
+		return converter.Asset{}, &PluginError{Plugin: p.Path, Operation: PluginFetch, Err: fmt.Errorf("got %d assets, want 1", len(resp.Assets))}
+	}
+	return resp.Assets[0].converterAsset(), nil
+}
+
+// mergeFunc wraps a merge operation into a converter.MergeFunc, which cannot
+// return errors: on failure, the error is stored into errp and the existing
+// asset is returned unchanged. Converter.merge returns the stored error.
+func (p *Plugin) mergeFunc(operation string, errp *error) converter.MergeFunc {
+	return func(existing, incoming converter.Asset) converter.Asset {
+		merged, err := p.merge(operation, existing, incoming)
+		if err != nil {
+			*errp = err
+			return existing
+		}
+		return merged
+	}
+}
+
+// merge runs a merge operation.
+func (p *Plugin) merge(operation string, existing, incoming converter.Asset) (converter.Asset, error) {
+	req := &PluginRequest{Operation: operation}
+	var err error
+	if req.Existing, err = newPluginAsset(existing); err != nil {
+		return converter.Asset{}, &PluginError{Plugin: p.Path, Operation: operation, Err: err}
+	}
+	if req.Incoming, err = newPluginAsset(incoming); err != nil {
+		return converter.Asset{}, &PluginError{Plugin: p.Path, Operation: operation, Err: err}
+	}
+	resp, err := p.call(req)
+	if err != nil {
+		return converter.Asset{}, err
+	}
+	if len(resp.Assets) != 1 {
+		return converter.Asset{}, &PluginError{Plugin: p.Path, Operation: operation, Err: fmt.Errorf("got %d assets, want 1", len(resp.Assets))}
+	}
+	return resp.Assets[0].converterAsset(), nil
+}
+
+func (p *Plugin) resourceRequest(operation string, d converter.TerraformResourceData, config *converter.Config) *PluginRequest {
+	req := &PluginRequest{
+		Operation: operation,
+		Config: &PluginConfig{
+			Project: config.Project,
+			Region:  config.Region,
+			Zone:    config.Zone,
+		},
+	}
+	if rd, ok := d.(*FakeResourceData); ok {
+		req.ResourceType = rd.Kind()
+		req.Before = rd.before
+		req.After = rd.after
+	}
+	return req
+}
+
+// call runs the plugin with the request on stdin and reads the response from
+// stdout. The plugin is killed if it runs for longer than its timeout.
+func (p *Plugin) call(req *PluginRequest) (*PluginResponse, error) {
+	req.ProtocolVersion = PluginProtocolVersion
+	in, err := json.Marshal(req)
+	if err != nil {
+		return nil, &PluginError{Plugin: p.Path, Operation: req.Operation, Err: err}
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultPluginTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %v", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, &PluginError{Plugin: p.Path, Operation: req.Operation, Err: err}
+	}
+
+	resp := &PluginResponse{}
+	dec := json.NewDecoder(&stdout)
+	dec.UseNumber()
+	if err := dec.Decode(resp); err != nil {
+		return nil, &PluginError{Plugin: p.Path, Operation: req.Operation, Err: fmt.Errorf("reading response: %w", err)}
+	}
+	if resp.Error != "" {
+		return nil, &PluginError{Plugin: p.Path, Operation: req.Operation, Err: errors.New(resp.Error)}
+	}
+	return resp, nil
+}
+
+// merge calls a merge function, returning the error a plugin merge function
+// stored into c.mergeErr (see Plugin.mergeFunc).
+func (c *Converter) merge(fn converter.MergeFunc, existing, incoming converter.Asset) (converter.Asset, error) {
+	c.mergeErr = nil
+	merged := fn(existing, incoming)
+	if err := c.mergeErr; err != nil {
+		c.mergeErr = nil
+		return converter.Asset{}, err
+	}
+	return merged, nil
+}
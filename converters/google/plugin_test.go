@@ -0,0 +1,217 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHelperPlugin is not a real test: it is the converter plugin run by
+// the plugin tests, see writeTestPlugin.
+func TestHelperPlugin(t *testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PLUGIN") != "1" {
+		return
+	}
+	var req PluginRequest
+	if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	json.NewEncoder(os.Stdout).Encode(testPluginResponse(&req))
+	os.Exit(0)
+}
+
+// testPluginResponse converts acme_thing resources into assets listing their
+// members, and merges the members of assets with the same name.
+func testPluginResponse(req *PluginRequest) *PluginResponse {
+	switch req.Operation {
+	case PluginDescribe:
+		return &PluginResponse{
+			ProtocolVersion:   PluginProtocolVersion,
+			ResourceTypes:     []string{"acme_thing"},
+			MergeCreateUpdate: true,
+		}
+	case PluginConvert:
+		if req.After == nil {
+			return &PluginResponse{NoConversion: true}
+		}
+		return &PluginResponse{Assets: []PluginAsset{{
+			Name: fmt.Sprintf("//acme.example.com/projects/%s/things/%s", req.Config.Project, req.After["name"]),
+			Type: "acme.example.com/Thing",
+			Resource: &converter.AssetResource{
+				Version: "v1",
+				Data:    map[string]interface{}{"members": []interface{}{req.After["member"]}},
+			},
+		}}}
+	case PluginMergeCreateUpdate:
+		incoming := req.Incoming.Resource.Data["members"].([]interface{})
+		if incoming[0] == "fail-merge" {
+			return &PluginResponse{Error: "cannot merge"}
+		}
+		merged := *req.Existing
+		merged.Resource.Data["members"] = append(merged.Resource.Data["members"].([]interface{}), incoming...)
+		return &PluginResponse{Assets: []PluginAsset{merged}}
+	}
+	return &PluginResponse{Error: "unsupported operation " + req.Operation}
+}
+
+// writeTestPlugin writes a plugin to a new directory that runs
+// TestHelperPlugin.
+func writeTestPlugin(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin test uses a shell script")
+	}
+	dir, err := ioutil.TempDir("", "plugins")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	script := fmt.Sprintf("#!/bin/sh\nGO_WANT_HELPER_PLUGIN=1 exec %q -test.run=TestHelperPlugin\n", os.Args[0])
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, PluginPrefix+"acme"), []byte(script), 0755))
+	// Files that are not executable are not plugins.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, PluginPrefix+"README"), []byte("readme"), 0644))
+	return dir
+}
+
+func TestLoadPlugins(t *testing.T) {
+	plugins, err := LoadPlugins(writeTestPlugin(t), 0)
+	require.NoError(t, err)
+	require.Len(t, plugins, 1)
+	assert.Equal(t, []string{"acme_thing"}, plugins[0].ResourceTypes)
+	assert.False(t, plugins[0].Fetch)
+	assert.True(t, plugins[0].MergeCreateUpdate)
+	assert.False(t, plugins[0].MergeDelete)
+}
+
+func TestAddPlugins(t *testing.T) {
+	plugins, err := LoadPlugins(writeTestPlugin(t), 0)
+	require.NoError(t, err)
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddPlugins(plugins))
+
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("acme_thing", "alice", create, map[string]interface{}{"name": "a", "member": "alice"}),
+		newTestResourceChange("acme_thing", "bob", create, map[string]interface{}{"name": "a", "member": "bob"}),
+		newTestResourceChange("acme_thing", "carol", create, map[string]interface{}{"name": "b", "member": "carol"}),
+	})
+	require.NoError(t, err)
+
+	assets := c.Assets()
+	require.Len(t, assets, 2)
+	assert.Equal(t, "//acme.example.com/projects/test-project/things/a", assets[0].Name)
+	assert.Equal(t, []interface{}{"alice", "bob"}, assets[0].Resource.Data["members"])
+	assert.Len(t, assets[0].Provenance, 2)
+	assert.Equal(t, []interface{}{"carol"}, assets[1].Resource.Data["members"])
+	for _, cov := range c.Coverage() {
+		assert.Equal(t, CoverageConverted, cov.Status, cov.Address)
+	}
+}
+
+func TestAddPlugins_mergeError(t *testing.T) {
+	plugins, err := LoadPlugins(writeTestPlugin(t), 0)
+	require.NoError(t, err)
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddPlugins(plugins))
+
+	create := tfjson.Actions{"create"}
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{
+		newTestResourceChange("acme_thing", "alice", create, map[string]interface{}{"name": "a", "member": "alice"}),
+		newTestResourceChange("acme_thing", "fail-merge", create, map[string]interface{}{"name": "a", "member": "fail-merge"}),
+	})
+	var pluginErr *PluginError
+	require.True(t, errors.As(err, &pluginErr), "error = %v", err)
+	assert.Equal(t, PluginMergeCreateUpdate, pluginErr.Operation)
+	assert.Contains(t, err.Error(), "cannot merge")
+}
+
+func TestPluginAsset(t *testing.T) {
+	policy := &V2OrgPolicy{
+		Name: "projects/test-project/policies/gcp.resourceLocations",
+		Spec: &V2OrgPolicySpec{Rules: []V2OrgPolicyRule{{Values: &V2OrgPolicyValues{AllowedValues: []string{"in:eu-locations"}}}}},
+	}
+	asset := converter.Asset{
+		Name: "//cloudresourcemanager.googleapis.com/projects/test-project",
+		Type: "cloudresourcemanager.googleapis.com/Project",
+		IAMPolicy: &converter.IAMPolicy{Bindings: []converter.IAMBinding{
+			{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+			{Role: conditionalRole("roles/viewer", testCondition), Members: []string{"user:bob@example.com"}},
+			{Role: auditLogConfigRole("allServices", "DATA_READ"), Members: []string{auditLogConfigMember}},
+		}},
+		OrgPolicy: []*converter.OrgPolicy{
+			{Constraint: "constraints/compute.disableSerialPortAccess", BooleanPolicy: &converter.BooleanPolicy{Enforced: true}},
+			{Constraint: v2OrgPolicyConstraint(policy)},
+		},
+	}
+
+	// Plugins see the conditions, audit configs and v2 organization
+	// policies, not their encoding.
+	pa, err := newPluginAsset(asset)
+	require.NoError(t, err)
+	b, err := json.Marshal(pa)
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), v2OrgPolicyPrefix)
+	assert.NotContains(t, string(b), auditLogConfigMember)
+	assert.Equal(t, []AuditConfig{{Service: "allServices", AuditLogConfigs: []AuditLogConfig{{LogType: "DATA_READ"}}}}, pa.IAMPolicy.AuditConfigs)
+	assert.Equal(t, []IAMBinding{
+		{Role: "roles/viewer", Members: []string{"user:alice@example.com"}},
+		{Role: "roles/viewer", Members: []string{"user:bob@example.com"}, Condition: testCondition},
+	}, pa.IAMPolicy.Bindings)
+	require.Len(t, pa.OrgPolicy, 1)
+	assert.Equal(t, "constraints/compute.disableSerialPortAccess", pa.OrgPolicy[0].Constraint)
+	assert.Equal(t, []*V2OrgPolicy{policy}, pa.V2OrgPolicies)
+
+	var got PluginAsset
+	require.NoError(t, json.Unmarshal(b, &got))
+	assert.Equal(t, asset, got.converterAsset())
+
+	asset.IAMPolicy.Bindings[0].Role = "roles/viewer\nroles/editor"
+	_, err = newPluginAsset(asset)
+	assert.Error(t, err)
+}
+
+func TestAddPlugins_alreadySupported(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	err = c.AddPlugins([]*Plugin{{Path: "acme", ResourceTypes: []string{"google_compute_disk"}}})
+	assert.Error(t, err)
+}
+
+func TestLoadPlugins_timeout(t *testing.T) {
+	dir := writeTestPlugin(t)
+	script := "#!/bin/sh\nexec sleep 10\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, PluginPrefix+"acme"), []byte(script), 0755))
+
+	start := time.Now()
+	_, err := LoadPlugins(dir, 100*time.Millisecond)
+	var pluginErr *PluginError
+	require.True(t, errors.As(err, &pluginErr), "error = %v", err)
+	assert.Equal(t, PluginDescribe, pluginErr.Operation)
+	assert.Contains(t, err.Error(), "timed out after 100ms")
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
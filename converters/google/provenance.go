@@ -0,0 +1,131 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"fmt"
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
+)
+
+// Provenance links an asset back to a terraform resource change that
+// contributed to it. Several resources contribute to one asset when their
+// conversions are merged, e.g. IAM members on the same project.
+type Provenance struct {
+	// Address is the resource instance address in the plan.
+	Address string `json:"address"`
+	// PreviousAddress is the address the resource was moved from, if any.
+	PreviousAddress string `json:"previous_address,omitempty"`
+	// ModuleAddress is the address of the module containing the resource.
+	ModuleAddress string `json:"module_address,omitempty"`
+	Type          string `json:"type"`
+	Provider      string `json:"provider"`
+	Action        string `json:"action"`
+	// SourcePlan is the plan the resource change was read from, if known.
+	SourcePlan string `json:"source_plan,omitempty"`
+	// Fields lists the asset fields the resource contributed, e.g.
+	// `iam_policy.bindings["roles/viewer"].members["user:jane@example.com"]`.
+	Fields []string `json:"fields,omitempty"`
+	// Removed lists the asset fields the deletion of the resource removed
+	// from the asset, e.g. the member of a deleted IAM member resource.
+	Removed []string `json:"removed,omitempty"`
+}
+
+func (c *Converter) newProvenance(plan string, rc *tfjson.ResourceChange, cai converter.Asset) (Provenance, error) {
+	fields, err := contributedFields(cai)
+	if err != nil {
+		return Provenance{}, fmt.Errorf("listing the fields of %v: %w", cai.Name, err)
+	}
+	return Provenance{
+		Address:         rc.Address,
+		PreviousAddress: c.moves[plan][rc.Address],
+		ModuleAddress:   rc.ModuleAddress,
+		Type:            rc.Type,
+		Provider:        rc.ProviderName,
+		Action:          tfplan.ActionOf(rc).String(),
+		SourcePlan:      plan,
+		Fields:          fields,
+	}, nil
+}
+
+// newRemovalProvenance is like newProvenance for a resource deleted from an
+// asset it was merged into: the fields of cai are removed from the asset.
+func (c *Converter) newRemovalProvenance(plan string, rc *tfjson.ResourceChange, cai converter.Asset) (Provenance, error) {
+	prov, err := c.newProvenance(plan, rc, cai)
+	prov.Fields, prov.Removed = nil, prov.Fields
+	return prov, err
+}
+
+// accessContextFields maps the Access Context Manager asset types to the
+// asset field holding their policy.
+var accessContextFields = map[string]string{
+	accessPolicyAssetType:     "access_policy",
+	accessLevelAssetType:      "access_level",
+	servicePerimeterAssetType: "service_perimeter",
+}
+
+// contributedFields lists the fields set by a converted asset before it is
+// merged with other assets.
+func contributedFields(cai converter.Asset) ([]string, error) {
+	if field, ok := accessContextFields[cai.Type]; ok && cai.Resource != nil {
+		if !isPerimeterResource(cai) {
+			return []string{field}, nil
+		}
+		var fields []string
+		for _, r := range perimeterResources(cai) {
+			fields = append(fields, fmt.Sprintf("service_perimeter.status.resources[%q]", r))
+		}
+		return fields, nil
+	}
+	var fields []string
+	if cai.Resource != nil {
+		var keys []string
+		for k := range cai.Resource.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fields = append(fields, "resource.data."+k)
+		}
+	}
+	if cai.IAMPolicy != nil {
+		others, auditConfigs := splitAuditConfigs(cai.IAMPolicy.Bindings)
+		bindings, err := splitConditionalBindings(others)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range bindings {
+			for _, m := range b.Members {
+				fields = append(fields, fmt.Sprintf("iam_policy.bindings[%s].members[%q]", bindingField(b), m))
+			}
+		}
+		for _, ac := range auditConfigs {
+			for _, lc := range ac.AuditLogConfigs {
+				fields = append(fields, fmt.Sprintf("iam_policy.audit_configs[%q].audit_log_configs[%q]", ac.Service, lc.LogType))
+			}
+		}
+	}
+	orgPolicies, v2OrgPolicies := splitV2OrgPolicies(cai.OrgPolicy)
+	for _, o := range orgPolicies {
+		fields = append(fields, fmt.Sprintf("org_policy[%q]", o.Constraint))
+	}
+	for _, p := range v2OrgPolicies {
+		fields = append(fields, fmt.Sprintf("v2_org_policies[%q]", p.Name))
+	}
+	return fields, nil
+}
@@ -0,0 +1,273 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// SchemaDrift is an attribute of a resource type that is present in the
+// plan, but unknown to the provider schema compiled into the converter (the
+// conversion schema), e.g. an attribute added by a newer provider version.
+// Such attributes are not converted.
+type SchemaDrift struct {
+	Type string `json:"type"`
+	// Attribute is the path of the attribute without list indices, e.g.
+	// "rule.log_config".
+	Attribute string `json:"attribute"`
+	// KnownToProvider reports whether the attribute is in the provider
+	// schemas given to SetProviderSchemas.
+	KnownToProvider bool `json:"known_to_provider"`
+	// Addresses lists the resources that set the attribute.
+	Addresses []string `json:"addresses"`
+}
+
+// SetProviderSchemas reads resource values with the schemas of the google
+// providers used by the plans (the output of `terraform providers schema
+// -json`) where they differ from the conversion schema: attributes that only
+// the given schemas know are read rather than dropped. Attributes known to
+// both keep their conversion schema, which the converters are written for.
+// Resource types unknown to the conversion schema (e.g. converted with
+// declarative mappings) are read with the given schemas.
+// It must be called before resource changes are added.
+func (c *Converter) SetProviderSchemas(schemas *tfjson.ProviderSchemas) {
+	c.readSchemas = make(map[string]map[string]*schema.Schema)
+
+	var names []string
+	for name := range schemas.Schemas {
+		if isGoogleProvider(name) {
+			names = append(names, name)
+		}
+	}
+	// Merge google before google-beta, whose schemas are a superset.
+	sort.Strings(names)
+	for _, name := range names {
+		for kind, s := range schemas.Schemas[name].ResourceSchemas {
+			if s == nil || s.Block == nil {
+				continue
+			}
+			base, ok := c.readSchemas[kind]
+			if resource, compiled := c.schema.ResourcesMap[kind]; !ok && compiled {
+				base = resource.Schema
+			}
+			c.readSchemas[kind] = mergeSchemas(base, blockSchema(s.Block))
+		}
+	}
+}
+
+// SchemaDrift lists the attributes of the resource changes added to the
+// converter that are unknown to the conversion schema, sorted by resource
+// type and attribute.
+func (c *Converter) SchemaDrift() []SchemaDrift {
+	drift := make([]SchemaDrift, 0, len(c.drift))
+	for _, d := range c.drift {
+		drift = append(drift, *d)
+	}
+	sort.Slice(drift, func(i, j int) bool {
+		if drift[i].Type != drift[j].Type {
+			return drift[i].Type < drift[j].Type
+		}
+		return drift[i].Attribute < drift[j].Attribute
+	})
+	return drift
+}
+
+// hasSchema reports whether the values of a resource type can be read.
+// Resource types converted by mappings or plugins can always be read.
+func (c *Converter) hasSchema(kind string) bool {
+	_, compiled := c.schema.ResourcesMap[kind]
+	_, read := c.readSchemas[kind]
+	_, mapped := c.mapperFuncs[kind]
+	return compiled || read || mapped
+}
+
+// resourceSchema returns the schema used to read the values of a resource
+// type. Resource types that are only known to a mapping or plugin are read
+// with a schema inferred from the values.
+func (c *Converter) resourceSchema(kind string, values map[string]interface{}) map[string]*schema.Schema {
+	if s, ok := c.readSchemas[kind]; ok {
+		return s
+	}
+	if r, ok := c.schema.ResourcesMap[kind]; ok {
+		return r.Schema
+	}
+	if s, ok := builtinSchemas[kind]; ok {
+		return s
+	}
+	return inferSchema(values)
+}
+
+// recordSchemaDrift records the attributes of the values of a resource change
+// that are unknown to the conversion schema. dropped lists the attributes the
+// resource data could not read.
+func (c *Converter) recordSchemaDrift(rc *tfjson.ResourceChange, values map[string]interface{}, dropped []string) {
+	resource, ok := c.schema.ResourcesMap[rc.Type]
+	if !ok {
+		// The whole resource type is unknown to the conversion schema.
+		return
+	}
+	_, merged := c.readSchemas[rc.Type]
+	unknown := dropped
+	if merged {
+		unknown = droppedAttributes(values, resource.Schema)
+	}
+	if len(unknown) == 0 {
+		return
+	}
+
+	droppedSet := make(map[string]bool, len(dropped))
+	for _, p := range dropped {
+		droppedSet[p] = true
+	}
+	if c.drift == nil {
+		c.drift = make(map[string]*SchemaDrift)
+	}
+	for _, p := range unknown {
+		attr := schemaAttribute(p)
+		key := rc.Type + " " + attr
+		d, ok := c.drift[key]
+		if !ok {
+			d = &SchemaDrift{Type: rc.Type, Attribute: attr}
+			c.drift[key] = d
+		}
+		d.KnownToProvider = d.KnownToProvider || (merged && !droppedSet[p])
+		if n := len(d.Addresses); n == 0 || d.Addresses[n-1] != rc.Address {
+			d.Addresses = append(d.Addresses, rc.Address)
+		}
+	}
+}
+
+// schemaAttribute removes the list indices from an attribute path, e.g.
+// "rule.0.log_config" becomes "rule.log_config".
+func schemaAttribute(p string) string {
+	parts := strings.Split(p, ".")
+	attr := parts[:0]
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil {
+			attr = append(attr, part)
+		}
+	}
+	return strings.Join(attr, ".")
+}
+
+func isGoogleProvider(name string) bool {
+	switch path.Base(name) {
+	case "google", "google-beta":
+		return true
+	}
+	return false
+}
+
+// mergeSchemas returns the base schema with the attributes that are only in
+// the other schema, including attributes of nested blocks.
+func mergeSchemas(base, other map[string]*schema.Schema) map[string]*schema.Schema {
+	merged := make(map[string]*schema.Schema, len(base))
+	for k, s := range base {
+		merged[k] = s
+	}
+	for k, o := range other {
+		s, ok := base[k]
+		if !ok {
+			merged[k] = o
+			continue
+		}
+		baseElem, ok := s.Elem.(*schema.Resource)
+		if !ok {
+			continue
+		}
+		otherElem, ok := o.Elem.(*schema.Resource)
+		if !ok || s.Type != o.Type {
+			continue
+		}
+		block := *s
+		block.Elem = &schema.Resource{Schema: mergeSchemas(baseElem.Schema, otherElem.Schema)}
+		merged[k] = &block
+	}
+	return merged
+}
+
+// blockSchema converts a block of a JSON provider schema to SDK schemas.
+// Attributes and blocks that have no SDK equivalent (e.g. object attributes)
+// are left out.
+func blockSchema(block *tfjson.SchemaBlock) map[string]*schema.Schema {
+	result := make(map[string]*schema.Schema, len(block.Attributes)+len(block.NestedBlocks))
+	for k, a := range block.Attributes {
+		s := typeSchema(a.AttributeType)
+		if s == nil {
+			continue
+		}
+		s.Optional = a.Optional
+		s.Required = a.Required
+		s.Computed = a.Computed
+		result[k] = s
+	}
+	for k, b := range block.NestedBlocks {
+		if b.Block == nil {
+			continue
+		}
+		s := &schema.Schema{
+			Elem:     &schema.Resource{Schema: blockSchema(b.Block)},
+			Optional: b.MinItems == 0,
+			Required: b.MinItems > 0,
+			MaxItems: int(b.MaxItems),
+		}
+		switch b.NestingMode {
+		case tfjson.SchemaNestingModeList:
+			s.Type = schema.TypeList
+		case tfjson.SchemaNestingModeSet:
+			s.Type = schema.TypeSet
+		default:
+			continue
+		}
+		result[k] = s
+	}
+	return result
+}
+
+// typeSchema converts the type of an attribute to an SDK schema, or returns
+// nil if the SDK has no equivalent. Numbers are read as floats, as the type
+// does not tell integers apart.
+func typeSchema(t cty.Type) *schema.Schema {
+	switch {
+	case t == cty.String:
+		return &schema.Schema{Type: schema.TypeString}
+	case t == cty.Number:
+		return &schema.Schema{Type: schema.TypeFloat}
+	case t == cty.Bool:
+		return &schema.Schema{Type: schema.TypeBool}
+	case t.IsListType(), t.IsSetType(), t.IsMapType():
+		elem := typeSchema(t.ElementType())
+		if elem == nil {
+			return nil
+		}
+		s := &schema.Schema{Type: schema.TypeList, Elem: elem}
+		if t.IsSetType() {
+			s.Type = schema.TypeSet
+		} else if t.IsMapType() {
+			if elem.Type == schema.TypeList || elem.Type == schema.TypeSet || elem.Type == schema.TypeMap {
+				return nil
+			}
+			s.Type = schema.TypeMap
+		}
+		return s
+	}
+	return nil
+}
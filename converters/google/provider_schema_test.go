@@ -0,0 +1,148 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// testProviderSchemas is the schema of a newer google provider, with
+// attributes that the conversion schema does not know.
+const testProviderSchemas = `
+{
+	"format_version": "0.2",
+	"provider_schemas": {
+		"registry.terraform.io/hashicorp/google": {
+			"resource_schemas": {
+				"google_compute_disk": {
+					"version": 0,
+					"block": {
+						"attributes": {
+							"name": {"type": "string", "required": true},
+							"new_attribute": {"type": "string", "optional": true},
+							"new_sizes": {"type": ["list", "number"], "optional": true}
+						},
+						"block_types": {
+							"disk_encryption_key": {
+								"nesting_mode": "list",
+								"block": {
+									"attributes": {
+										"raw_key": {"type": "string", "optional": true},
+										"new_key_attribute": {"type": "string", "optional": true}
+									}
+								},
+								"max_items": 1
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+`
+
+func TestSetProviderSchemas(t *testing.T) {
+	schemas := &tfjson.ProviderSchemas{}
+	require.NoError(t, schemas.UnmarshalJSON([]byte(testProviderSchemas)))
+
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	c.SetProviderSchemas(schemas)
+
+	disk := newTestResourceChange("google_compute_disk", "a", tfjson.Actions{"create"}, map[string]interface{}{
+		"project":           testProject,
+		"name":              "a",
+		"zone":              "us-central1-a",
+		"new_attribute":     "from a newer provider",
+		"new_sizes":         []interface{}{1.0, 2.5},
+		"unknown_attribute": "unknown to both schemas",
+		"disk_encryption_key": []interface{}{
+			map[string]interface{}{"raw_key": "key", "new_key_attribute": "nested"},
+		},
+	})
+	rd, err := c.newResourceData(disk, disk.Change.After)
+	require.NoError(t, err)
+	assert.Equal(t, "from a newer provider", rd.Get("new_attribute"))
+	assert.Equal(t, []interface{}{1.0, 2.5}, rd.Get("new_sizes"))
+	assert.Equal(t, "nested", rd.Get("disk_encryption_key.0.new_key_attribute"))
+	// Attributes known to both schemas keep the conversion schema.
+	assert.Equal(t, "key", rd.Get("disk_encryption_key.0.raw_key"))
+	assert.Equal(t, "us-central1-a", rd.Get("zone"))
+	assert.Equal(t, []string{"unknown_attribute"}, rd.DroppedAttributes())
+
+	assert.Equal(t, []SchemaDrift{
+		{Type: "google_compute_disk", Attribute: "disk_encryption_key.new_key_attribute", KnownToProvider: true, Addresses: []string{disk.Address}},
+		{Type: "google_compute_disk", Attribute: "new_attribute", KnownToProvider: true, Addresses: []string{disk.Address}},
+		{Type: "google_compute_disk", Attribute: "new_sizes", KnownToProvider: true, Addresses: []string{disk.Address}},
+		{Type: "google_compute_disk", Attribute: "unknown_attribute", KnownToProvider: false, Addresses: []string{disk.Address}},
+	}, c.SchemaDrift())
+}
+
+func TestSchemaDrift(t *testing.T) {
+	create := tfjson.Actions{"create"}
+	changes := []*tfjson.ResourceChange{
+		newTestResourceChange("google_compute_disk", "a", create, map[string]interface{}{
+			"project": testProject, "name": "a", "zone": "us-central1-a", "new_attribute": "a",
+		}),
+		newTestResourceChange("google_compute_disk", "b", create, map[string]interface{}{
+			"project": testProject, "name": "b", "zone": "us-central1-a", "new_attribute": "b",
+		}),
+		newTestResourceChange("google_compute_disk", "c", create, map[string]interface{}{
+			"project": testProject, "name": "c", "zone": "us-central1-a",
+		}),
+	}
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	require.NoError(t, c.AddResourceChanges(changes))
+
+	assert.Equal(t, []SchemaDrift{
+		{Type: "google_compute_disk", Attribute: "new_attribute", Addresses: []string{changes[0].Address, changes[1].Address}},
+	}, c.SchemaDrift())
+}
+
+func TestTypeSchema(t *testing.T) {
+	cases := []struct {
+		name string
+		typ  cty.Type
+		want *schema.Schema
+	}{
+		{"String", cty.String, &schema.Schema{Type: schema.TypeString}},
+		{"Number", cty.Number, &schema.Schema{Type: schema.TypeFloat}},
+		{"Bool", cty.Bool, &schema.Schema{Type: schema.TypeBool}},
+		{"List", cty.List(cty.String), &schema.Schema{Type: schema.TypeList, Elem: &schema.Schema{Type: schema.TypeString}}},
+		{"Set", cty.Set(cty.Number), &schema.Schema{Type: schema.TypeSet, Elem: &schema.Schema{Type: schema.TypeFloat}}},
+		{"Map", cty.Map(cty.Bool), &schema.Schema{Type: schema.TypeMap, Elem: &schema.Schema{Type: schema.TypeBool}}},
+		{"MapOfLists", cty.Map(cty.List(cty.String)), nil},
+		{"Object", cty.Object(map[string]cty.Type{"a": cty.String}), nil},
+		{"Dynamic", cty.DynamicPseudoType, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, typeSchema(c.typ))
+		})
+	}
+}
+
+func TestSchemaAttribute(t *testing.T) {
+	assert.Equal(t, "rule.log_config.metadata", schemaAttribute("rule.0.log_config.12.metadata"))
+	assert.Equal(t, "name", schemaAttribute("name"))
+}
@@ -0,0 +1,94 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"encoding/json"
+	errorssyslib "errors"
+	"fmt"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// ResourceError is an error converting a single resource change.
+type ResourceError struct {
+	Address string
+	Type    string
+	// SourcePlan is the plan the resource change was read from, if known.
+	SourcePlan string
+	Err        error
+}
+
+func (e *ResourceError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Address, e.Err)
+}
+
+func (e *ResourceError) Unwrap() error {
+	return e.Err
+}
+
+// Chain lists the messages of the wrapped errors, outermost first.
+func (e *ResourceError) Chain() []string {
+	var chain []string
+	for err := e.Err; err != nil; err = errorssyslib.Unwrap(err) {
+		msg := err.Error()
+		// Errors annotated with a stack trace repeat the message of the
+		// error they wrap.
+		if len(chain) > 0 && chain[len(chain)-1] == msg {
+			continue
+		}
+		chain = append(chain, msg)
+	}
+	return chain
+}
+
+// MarshalJSON encodes the error with its message and chain.
+func (e *ResourceError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Address    string   `json:"address"`
+		Type       string   `json:"type"`
+		SourcePlan string   `json:"source_plan,omitempty"`
+		Error      string   `json:"error"`
+		Chain      []string `json:"chain"`
+	}{e.Address, e.Type, e.SourcePlan, e.Err.Error(), e.Chain()})
+}
+
+// SetContinueOnError sets whether the converter keeps converting the other
+// resource changes when converting one of them fails. The failures are
+// available from Errors instead of being returned by AddResourceChanges.
+func (c *Converter) SetContinueOnError(continueOnError bool) {
+	c.continueOnError = continueOnError
+}
+
+// Errors lists the resource changes that could not be converted while
+// continuing on errors (see SetContinueOnError).
+func (c *Converter) Errors() []*ResourceError {
+	return c.errors
+}
+
+// handleResourceError returns err wrapped with msg, or records it and
+// returns nil if the converter continues on errors.
+func (c *Converter) handleResourceError(plan string, rc *tfjson.ResourceChange, msg string, err error) error {
+	err = fmt.Errorf("%s %w", msg, err)
+	if !c.continueOnError {
+		return err
+	}
+	c.errors = append(c.errors, &ResourceError{
+		Address:    rc.Address,
+		Type:       rc.Type,
+		SourcePlan: plan,
+		Err:        err,
+	})
+	return nil
+}
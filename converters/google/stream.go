@@ -0,0 +1,60 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
+	"github.com/pkg/errors"
+)
+
+// SetStream sets a function that is passed the assets that no later
+// resource change of the plan can merge into as soon as they are converted,
+// e.g. to write the assets of large plans while the plan is being converted.
+// These are the assets of mappers without merge functions (e.g. buckets, but
+// not projects, which IAM members are merged into). Streamed assets are left
+// out of Assets, and they have no Prior. A resource change of a later plan
+// that changes a streamed asset adds it back to Assets, so streaming is meant
+// for the conversion of a single plan. An error of stream aborts the
+// conversion.
+func (c *Converter) SetStream(stream func(Asset) error) {
+	c.stream = stream
+}
+
+// final reports whether no later resource change can merge into the assets
+// of mapper, see SetStream.
+func final(mapper converter.Mapper) bool {
+	return mapper.MergeCreateUpdate == nil && mapper.MergeDelete == nil
+}
+
+// streamAssets passes the final assets converted since the last call to the
+// stream function, see SetStream.
+func (c *Converter) streamAssets() error {
+	keys := c.final
+	c.final = nil
+	if c.stream == nil {
+		return nil
+	}
+	for _, key := range keys {
+		asset, ok := c.assets[key]
+		if !ok || c.streamed[key] {
+			continue
+		}
+		if err := c.stream(asset); err != nil {
+			return errors.Wrapf(err, "streaming asset %s", asset.Name)
+		}
+		c.streamed[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"errors"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetStream(t *testing.T) {
+	create := tfjson.Actions{"create"}
+	changes := []*tfjson.ResourceChange{
+		newTestResourceChange("google_storage_bucket", "a", create, map[string]interface{}{
+			"name": "a", "location": "EU", "project": testProject,
+		}),
+		newTestResourceChange("google_project_iam_member", "m", create, map[string]interface{}{
+			"project": testProject, "role": "roles/viewer", "member": "user:jane@example.com",
+		}),
+		newTestResourceChange("google_storage_bucket", "b", create, map[string]interface{}{
+			"name": "b", "location": "EU", "project": testProject,
+		}),
+	}
+
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	var streamed []string
+	c.SetStream(func(asset Asset) error {
+		// Assets are streamed in the order of the plan, while it is
+		// converted.
+		assert.Len(t, c.Coverage(), len(streamed)*2+1)
+		streamed = append(streamed, asset.Name)
+		return nil
+	})
+	require.NoError(t, c.AddResourceChanges(changes))
+	assert.Equal(t, []string{"//storage.googleapis.com/a", "//storage.googleapis.com/b"}, streamed)
+	// The project IAM policy could still be merged into.
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, "//cloudresourcemanager.googleapis.com/projects/"+testProject, assets[0].Name)
+
+	c, err = newTestConverter()
+	require.NoError(t, err)
+	c.SetContinueOnError(true)
+	c.SetStream(func(asset Asset) error { return errors.New("disk full") })
+	err = c.AddResourceChanges(changes)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "disk full")
+}
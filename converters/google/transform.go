@@ -0,0 +1,284 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package google
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Transformer post-processes converted assets. Transformers run on every
+// asset after it has been converted, merged and augmented, and before it is
+// stored, so their changes are seen by Assets and by validation.
+type Transformer interface {
+	Transform(asset *Asset) error
+}
+
+// TransformerFunc adapts a function to the Transformer interface.
+type TransformerFunc func(asset *Asset) error
+
+// Transform calls f(asset).
+func (f TransformerFunc) Transform(asset *Asset) error {
+	return f(asset)
+}
+
+// AddTransformers registers transformers, which run in the order they were
+// added.
+func (c *Converter) AddTransformers(transformers ...Transformer) {
+	c.transformers = append(c.transformers, transformers...)
+}
+
+// transform runs the registered transformers on an augmented asset.
+func (c *Converter) transform(asset Asset) (Asset, error) {
+	for _, t := range c.transformers {
+		if err := t.Transform(&asset); err != nil {
+			return Asset{}, fmt.Errorf("%s: %w", asset.Name, err)
+		}
+	}
+	return asset, nil
+}
+
+// FieldTransform is a built-in transformer that sets, renames and deletes
+// fields of the assets it applies to. Paths are dot-separated keys of the
+// JSON representation of the asset, e.g. "resource.data.labels.env". For
+// example:
+//
+//	asset_types: ["compute.googleapis.com/*"]
+//	ancestry: organization/my-org/folder/finance
+//	set:
+//	  resource.data.labels.cost-center: cc-42
+//	rename:
+//	  resource.data.labels.Env: resource.data.labels.env
+//	delete:
+//	  - resource.data.labels.tmp
+type FieldTransform struct {
+	// AssetTypes are glob patterns of the asset types to transform. All
+	// asset types are transformed if it is empty.
+	AssetTypes []string `yaml:"asset_types"`
+	// Ancestry is a glob pattern of the ancestry path of the assets to
+	// transform. It matches the assets whose ancestry path or any of its
+	// ancestors matches, e.g. "organization/*/folder/finance" matches
+	// "organization/my-org/folder/finance/project/my-project".
+	Ancestry string `yaml:"ancestry"`
+
+	// Rename, Set and Delete are applied in that order.
+	Rename map[string]string      `yaml:"rename"`
+	Set    map[string]interface{} `yaml:"set"`
+	Delete []string               `yaml:"delete"`
+}
+
+// transformsFile is the format of the file read by LoadTransforms.
+type transformsFile struct {
+	Transforms []*FieldTransform `yaml:"transforms"`
+}
+
+// LoadTransforms loads the built-in transforms listed under the
+// "transforms" key of a YAML file.
+func LoadTransforms(file string) ([]Transformer, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening transforms file")
+	}
+	var f transformsFile
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "reading transforms from %s", file)
+	}
+	var transformers []Transformer
+	for i, t := range f.Transforms {
+		if err := t.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid transform %d in %s", i, file)
+		}
+		transformers = append(transformers, t)
+	}
+	return transformers, nil
+}
+
+// Validate checks the patterns and paths of the transform.
+func (t *FieldTransform) Validate() error {
+	for _, p := range append(append([]string{}, t.AssetTypes...), t.Ancestry) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("pattern %q: %w", p, err)
+		}
+	}
+	paths := append([]string{}, t.Delete...)
+	for from, to := range t.Rename {
+		paths = append(paths, from, to)
+	}
+	for p := range t.Set {
+		paths = append(paths, p)
+	}
+	for _, p := range paths {
+		for _, k := range strings.Split(p, ".") {
+			if k == "" {
+				return fmt.Errorf("path %q: empty key", p)
+			}
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the transform applies to the asset.
+func (t *FieldTransform) Matches(asset *Asset) bool {
+	if len(t.AssetTypes) > 0 {
+		matched := false
+		for _, p := range t.AssetTypes {
+			if ok, _ := path.Match(p, asset.Type); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if t.Ancestry != "" {
+		segments := strings.Split(asset.Ancestry, "/")
+		for i := len(segments); i > 0; i-- {
+			if ok, _ := path.Match(t.Ancestry, strings.Join(segments[:i], "/")); ok {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Transform applies the transform to the asset if it matches. The asset is
+// edited in its JSON representation and decoded back, so fields set outside
+// of the Asset format (e.g. "foo.bar") are dropped.
+func (t *FieldTransform) Transform(asset *Asset) error {
+	if !t.Matches(asset) {
+		return nil
+	}
+	data, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	var obj map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return err
+	}
+
+	for _, from := range sortedKeys(t.Rename) {
+		v, ok := deleteJSONPath(obj, strings.Split(from, "."))
+		if !ok {
+			continue
+		}
+		if err := setJSONPath(obj, strings.Split(t.Rename[from], "."), v); err != nil {
+			return errors.Wrapf(err, "renaming %s", from)
+		}
+	}
+	setPaths := make([]string, 0, len(t.Set))
+	for p := range t.Set {
+		setPaths = append(setPaths, p)
+	}
+	sort.Strings(setPaths)
+	for _, p := range setPaths {
+		if err := setJSONPath(obj, strings.Split(p, "."), jsonValue(t.Set[p])); err != nil {
+			return errors.Wrapf(err, "setting %s", p)
+		}
+	}
+	for _, p := range t.Delete {
+		deleteJSONPath(obj, strings.Split(p, "."))
+	}
+
+	if data, err = json.Marshal(obj); err != nil {
+		return err
+	}
+	transformed := Asset{
+		SourcePlans:    asset.SourcePlans,
+		Provenance:     asset.Provenance,
+		converterAsset: asset.converterAsset,
+	}
+	dec = json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&transformed); err != nil {
+		return errors.Wrap(err, "decoding transformed asset")
+	}
+	*asset = transformed
+	return nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// setJSONPath sets the value at the given path of nested objects, creating
+// missing objects.
+func setJSONPath(obj map[string]interface{}, keys []string, v interface{}) error {
+	for i, k := range keys[:len(keys)-1] {
+		switch next := obj[k].(type) {
+		case map[string]interface{}:
+			obj = next
+		case nil:
+			m := make(map[string]interface{})
+			obj[k] = m
+			obj = m
+		default:
+			return fmt.Errorf("%s is not an object", strings.Join(keys[:i+1], "."))
+		}
+	}
+	obj[keys[len(keys)-1]] = v
+	return nil
+}
+
+// deleteJSONPath removes the value at the given path of nested objects and
+// returns it, if it exists.
+func deleteJSONPath(obj map[string]interface{}, keys []string) (interface{}, bool) {
+	for _, k := range keys[:len(keys)-1] {
+		next, ok := obj[k].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		obj = next
+	}
+	k := keys[len(keys)-1]
+	v, ok := obj[k]
+	delete(obj, k)
+	return v, ok
+}
+
+// jsonValue converts a value decoded from YAML into a JSON value.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(t))
+		for i, e := range t {
+			list[i] = jsonValue(e)
+		}
+		return list
+	}
+	return v
+}
@@ -0,0 +1,169 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package google
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTransformAsset() *Asset {
+	return &Asset{
+		Name:     "//compute.googleapis.com/projects/my-project/zones/us-central1-a/disks/my-disk",
+		Type:     "compute.googleapis.com/Disk",
+		Ancestry: "organization/my-org/folder/finance/project/my-project",
+		Resource: &AssetResource{
+			Version: "v1",
+			Data: map[string]interface{}{
+				"sizeGb": json.Number("10"),
+				"labels": map[string]interface{}{"Env": "dev", "tmp": "x"},
+			},
+		},
+		SourcePlans: []string{"plan.json"},
+	}
+}
+
+func TestFieldTransform(t *testing.T) {
+	ft := &FieldTransform{
+		AssetTypes: []string{"compute.googleapis.com/*"},
+		Ancestry:   "organization/*/folder/finance",
+		Rename:     map[string]string{"resource.data.labels.Env": "resource.data.labels.env"},
+		Set: map[string]interface{}{
+			"resource.data.labels.cost-center": "cc-42",
+			"resource.data.options":            map[interface{}]interface{}{"a": []interface{}{1, "b"}},
+		},
+		Delete: []string{"resource.data.labels.tmp", "resource.data.missing.key"},
+	}
+	require.NoError(t, ft.Validate())
+	asset := newTestTransformAsset()
+	require.NoError(t, ft.Transform(asset))
+
+	assert.Equal(t, map[string]interface{}{
+		"sizeGb":  json.Number("10"),
+		"labels":  map[string]interface{}{"env": "dev", "cost-center": "cc-42"},
+		"options": map[string]interface{}{"a": []interface{}{json.Number("1"), "b"}},
+	}, asset.Resource.Data)
+	assert.Equal(t, "v1", asset.Resource.Version)
+	assert.Equal(t, []string{"plan.json"}, asset.SourcePlans)
+}
+
+func TestFieldTransform_Matches(t *testing.T) {
+	cases := []struct {
+		name string
+		ft   FieldTransform
+		want bool
+	}{
+		{"NoFilter", FieldTransform{}, true},
+		{"AssetType", FieldTransform{AssetTypes: []string{"storage.googleapis.com/*", "compute.googleapis.com/Disk"}}, true},
+		{"OtherAssetType", FieldTransform{AssetTypes: []string{"storage.googleapis.com/*"}}, false},
+		{"Ancestor", FieldTransform{Ancestry: "organization/my-org"}, true},
+		{"AncestryPattern", FieldTransform{Ancestry: "organization/*/folder/finance/project/*"}, true},
+		{"OtherAncestry", FieldTransform{Ancestry: "organization/*/folder/sales"}, false},
+		{"BothFilters", FieldTransform{AssetTypes: []string{"compute.googleapis.com/*"}, Ancestry: "organization/*/folder/sales"}, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, c.ft.Matches(newTestTransformAsset()))
+		})
+	}
+}
+
+func TestFieldTransform_notAnObject(t *testing.T) {
+	ft := &FieldTransform{Set: map[string]interface{}{"resource.data.sizeGb.value": 1}}
+	err := ft.Transform(newTestTransformAsset())
+	assert.Error(t, err)
+}
+
+func TestLoadTransforms(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transforms")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, ioutil.WriteFile(valid, []byte(`
+transforms:
+- asset_types: ["compute.googleapis.com/*"]
+  set:
+    resource.data.labels.team: infra
+- delete: [iam_policy]
+`), 0644))
+	transformers, err := LoadTransforms(valid)
+	require.NoError(t, err)
+	require.Len(t, transformers, 2)
+	assert.Equal(t, []string{"compute.googleapis.com/*"}, transformers[0].(*FieldTransform).AssetTypes)
+
+	invalid := map[string]string{
+		"UnknownKey":   "transforms:\n- unknown: true\n",
+		"EmptyKey":     "transforms:\n- delete: [resource..data]\n",
+		"BadPattern":   "transforms:\n- asset_types: [\"[\"]\n",
+		"NotTheFormat": "- delete: [name]\n",
+	}
+	for name, content := range invalid {
+		t.Run(name, func(t *testing.T) {
+			file := filepath.Join(dir, name+".yaml")
+			require.NoError(t, ioutil.WriteFile(file, []byte(content), 0644))
+			_, err := LoadTransforms(file)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAddTransformers(t *testing.T) {
+	c, err := newTestConverter()
+	require.NoError(t, err)
+	c.AddTransformers(
+		TransformerFunc(func(asset *Asset) error {
+			asset.Resource.Data["labels"] = map[string]interface{}{"team": "infra"}
+			return nil
+		}),
+		&FieldTransform{Rename: map[string]string{"resource.data.labels.team": "resource.data.labels.owner"}},
+	)
+
+	rc := &tfjson.ResourceChange{
+		Address:      "google_compute_disk.default",
+		Mode:         "managed",
+		Type:         "google_compute_disk",
+		Name:         "default",
+		ProviderName: "registry.terraform.io/hashicorp/google",
+		Change: &tfjson.Change{
+			Actions: tfjson.Actions{"create"},
+			After: map[string]interface{}{
+				"name": "my-disk",
+				"zone": "us-central1-a",
+			},
+		},
+	}
+	require.NoError(t, c.AddResourceChanges([]*tfjson.ResourceChange{rc}))
+	assets := c.Assets()
+	require.Len(t, assets, 1)
+	assert.Equal(t, map[string]interface{}{"owner": "infra"}, assets[0].Resource.Data["labels"])
+	assert.Len(t, assets[0].Provenance, 1)
+
+	c, err = newTestConverter()
+	require.NoError(t, err)
+	c.AddTransformers(TransformerFunc(func(asset *Asset) error {
+		return errors.New("failed")
+	}))
+	err = c.AddResourceChanges([]*tfjson.ResourceChange{rc})
+	assert.Contains(t, err.Error(), "transforming asset")
+}
@@ -25,3 +25,12 @@ func multiEnvSearch(ks []string) string {
 	}
 	return ""
 }
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -17,6 +17,7 @@ package google
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	converter "github.com/GoogleCloudPlatform/terraform-google-conversion/google"
 )
@@ -38,6 +39,9 @@ func getProject(d converter.TerraformResourceData, config *converter.Config, cai
 		res, ok = d.GetOk("project_id")
 		if ok {
 			return res.(string), nil
+		} else if parent, ok := d.GetOk("parent"); ok && strings.HasPrefix(parent.(string), "projects/") {
+			// v2 organization policies set on the project.
+			return strings.TrimPrefix(parent.(string), "projects/"), nil
 		} else {
 			log.Printf("[WARN] Failed to retrieve project_id for %s from resource", cai.Name)
 		}
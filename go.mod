@@ -11,8 +11,10 @@ require (
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.1.3
 	github.com/stretchr/testify v1.7.0
+	github.com/zclconf/go-cty v1.5.1
 	google.golang.org/api v0.46.0
 	google.golang.org/genproto v0.0.0-20210503173045-b96a97608f20
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 go 1.14
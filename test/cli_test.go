@@ -343,7 +343,7 @@ func saveFile(t *testing.T, dir, filename string, payload []byte) {
 func tfvConvert(t *testing.T, dir, tfplan string, offline bool) []byte {
 	executable := tfvBinary
 	wantError := false
-	args := []string{"convert", "--project", data.Provider["project"]}
+	args := []string{"convert", "--project", data.Provider["project"], "--timestamp", data.Time["RFC3339Nano"]}
 	if offline {
 		args = append(args, "--offline", "--ancestry", data.Ancestry)
 	}
@@ -362,7 +362,7 @@ func tfvConvert(t *testing.T, dir, tfplan string, offline bool) []byte {
 
 func tfvValidate(t *testing.T, wantError bool, dir, tfplan, policyPath string, offline bool) []byte {
 	executable := tfvBinary
-	args := []string{"validate", "--project", data.Provider["project"], "--policy-path", policyPath}
+	args := []string{"validate", "--project", data.Provider["project"], "--policy-path", policyPath, "--timestamp", data.Time["RFC3339Nano"]}
 	if offline {
 		args = append(args, "--offline", "--ancestry", data.Ancestry)
 	}
@@ -2,6 +2,7 @@ package test
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -27,6 +28,9 @@ var (
 	data      *testData
 	tfvBinary string
 	tmpDir    = os.TempDir()
+	// Update time of the converted org policies, fixed with --timestamp so
+	// that the output matches the templates.
+	testTime = time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
 )
 
 // testData represents the full dataset that is used for templating terraform
@@ -85,8 +89,6 @@ func init() {
 		ancestry = defaultAncestry
 	}
 	providerVersion := defaultProviderVersion
-	//As time is not information in terraform resource data, time is fixed for testing purposes
-	fixedTime := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
 	data = &testData{
 		TFVersion: "0.12",
 		Provider: map[string]string{
@@ -95,7 +97,7 @@ func init() {
 			"credentials": credentials,
 		},
 		Time: map[string]string{
-			"RFC3339Nano": fixedTime.Format(time.RFC3339Nano),
+			"RFC3339Nano": testTime.Format(time.RFC3339Nano),
 		},
 		Project: map[string]string{
 			"Name":               "My Project Name",
@@ -115,6 +117,25 @@ func generateTestFiles(t *testing.T, sourceDir string, targetDir string, selecto
 			split := strings.Split(s, "/")
 			return split[len(split)-1]
 		},
+		// ancestors returns the JSON list of the ancestors of the ancestry
+		// path made of the given parts, closest ancestor first.
+		"ancestors": func(parts ...string) string {
+			split := strings.Split(strings.Join(parts, ""), "/")
+			var ancestors []string
+			for i := len(split) - 2; i >= 0; i -= 2 {
+				if split[i+1] != "unknown" {
+					ancestors = append(ancestors, split[i]+"s/"+split[i+1])
+				}
+			}
+			out, _ := json.Marshal(ancestors)
+			return string(out)
+		},
+		// ancestryParent returns the full name of the last node of an
+		// ancestry path, the parent of projects created in it.
+		"ancestryParent": func(s string) string {
+			split := strings.Split(s, "/")
+			return fmt.Sprintf("//cloudresourcemanager.googleapis.com/%ss/%s", split[len(split)-2], split[len(split)-1])
+		},
 	}
 	tmpls, err := template.New("").Funcs(funcMap).
 		ParseGlob(filepath.Join(sourceDir, selector))
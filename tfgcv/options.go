@@ -0,0 +1,169 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
+)
+
+// Option configures how planned assets are read.
+type Option func(*readOptions)
+
+type readOptions struct {
+	filter          tfplan.Filter
+	report          *Report
+	continueOnError bool
+	providerSchema  string
+	mappingsDir     string
+	pluginsDir      string
+	pluginTimeout   time.Duration
+	transformsFile  string
+	transformers    []google.Transformer
+	timestamp       time.Time
+	planTimestamp   bool
+	deletions       bool
+	stream          func(google.Asset) error
+}
+
+func newReadOptions(opts []Option) *readOptions {
+	o := &readOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithInclude only converts the resource changes matching at least one of
+// the given patterns (see tfplan.Filter).
+func WithInclude(patterns ...string) Option {
+	return func(o *readOptions) {
+		o.filter.Include = append(o.filter.Include, patterns...)
+	}
+}
+
+// WithExclude does not convert the resource changes matching any of the
+// given patterns (see tfplan.Filter).
+func WithExclude(patterns ...string) Option {
+	return func(o *readOptions) {
+		o.filter.Exclude = append(o.filter.Exclude, patterns...)
+	}
+}
+
+// WithReport records what happened to the resource changes of the plans
+// in the given report.
+func WithReport(report *Report) Option {
+	return func(o *readOptions) {
+		o.report = report
+	}
+}
+
+// WithContinueOnError keeps converting the other resource changes when
+// converting one of them fails. The assets that could be converted are
+// returned together with an *IncompleteError listing the failures.
+func WithContinueOnError() Option {
+	return func(o *readOptions) {
+		o.continueOnError = true
+	}
+}
+
+// WithProviderSchemaFile reads the resource values with the provider schemas
+// in the given file, the output of `terraform providers schema -json` (see
+// google.Converter.SetProviderSchemas).
+func WithProviderSchemaFile(path string) Option {
+	return func(o *readOptions) {
+		o.providerSchema = path
+	}
+}
+
+// WithMappingsDir converts the resource types of the declarative mappings in
+// the given directory (see google.LoadMappings).
+func WithMappingsDir(dir string) Option {
+	return func(o *readOptions) {
+		o.mappingsDir = dir
+	}
+}
+
+// WithPluginsDir converts resource types with the converter plugins in the
+// given directory (see google.LoadPlugins).
+func WithPluginsDir(dir string) Option {
+	return func(o *readOptions) {
+		o.pluginsDir = dir
+	}
+}
+
+// WithPluginTimeout sets the time a plugin operation may run for before the
+// plugin is killed (see google.Plugin.Timeout).
+func WithPluginTimeout(timeout time.Duration) Option {
+	return func(o *readOptions) {
+		o.pluginTimeout = timeout
+	}
+}
+
+// WithTransformers runs the given transformers on every converted asset
+// before it is returned or validated (see google.Converter.AddTransformers).
+func WithTransformers(transformers ...google.Transformer) Option {
+	return func(o *readOptions) {
+		o.transformers = append(o.transformers, transformers...)
+	}
+}
+
+// WithTransformsFile runs the built-in transforms of the given YAML file on
+// every converted asset (see google.LoadTransforms). They run before the
+// transformers given with WithTransformers.
+func WithTransformsFile(path string) Option {
+	return func(o *readOptions) {
+		o.transformsFile = path
+	}
+}
+
+// WithTimestamp uses the given time as the update time of converted assets
+// and org policies instead of the current time, e.g. for reproducible
+// conversions.
+func WithTimestamp(t time.Time) Option {
+	return func(o *readOptions) {
+		o.timestamp = t
+	}
+}
+
+// WithPlanTimestamp uses the time each plan was created at as the update time
+// of the assets and org policies converted from it. Plans that do not record their
+// creation time (before Terraform 1.5) use the time of WithTimestamp or the
+// current time.
+func WithPlanTimestamp() Option {
+	return func(o *readOptions) {
+		o.planTimestamp = true
+	}
+}
+
+// WithDeletions converts the resources deleted by the plans into tombstones:
+// assets marked as deleted, so that constraints can guard against deletions
+// (see google.Converter.SetDeletions).
+func WithDeletions() Option {
+	return func(o *readOptions) {
+		o.deletions = true
+	}
+}
+
+// WithStream passes the assets that no later resource change can merge into
+// to stream as soon as they are converted, instead of returning them (see
+// google.Converter.SetStream). It is meant for reading a single plan.
+func WithStream(stream func(google.Asset) error) Option {
+	return func(o *readOptions) {
+		o.stream = stream
+	}
+}
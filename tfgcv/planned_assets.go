@@ -19,7 +19,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"time"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"google.golang.org/api/option"
 
 	"github.com/GoogleCloudPlatform/terraform-validator/ancestrymanager"
@@ -31,32 +33,194 @@ import (
 // ReadPlannedAssets extracts CAI assets from a terraform plan file.
 // If ancestry path is provided, it assumes the project is in that path rather
 // than fetching the ancestry information using Google API.
-// It ignores non-supported resources.
-func ReadPlannedAssets(ctx context.Context, path, project, ancestry string, offline bool) ([]google.Asset, error) {
-	converter, err := newConverter(ctx, path, project, ancestry, offline)
+// It ignores non-supported resources and the resources filtered out by the
+// WithInclude and WithExclude options. With WithContinueOnError, it returns
+// the assets that could be converted together with an *IncompleteError.
+func ReadPlannedAssets(ctx context.Context, path, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	return ReadMergedPlannedAssets(ctx, []string{path}, project, ancestry, offline, opts...)
+}
+
+// ReadMergedPlannedAssets extracts CAI assets from several terraform plan
+// files into a single inventory, e.g. for plans of separate Terraform roots
+// that deploy into the same projects. Assets that several plans contribute to
+// are merged, and each asset lists its source plans in SourcePlans.
+func ReadMergedPlannedAssets(ctx context.Context, paths []string, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	plans, err := ReadPlanFiles(paths)
 	if err != nil {
 		return nil, err
 	}
+	return ConvertPlannedAssets(ctx, plans, project, ancestry, offline, opts...)
+}
+
+// ReadDriftedAssets extracts CAI assets from the resource drift section of a
+// terraform plan file, i.e. the objects that Terraform found to have been
+// changed outside of Terraform while refreshing state. The assets reflect the
+// refreshed objects, which are about to be captured into state.
+// It ignores non-supported resources. The options apply as for
+// ReadPlannedAssets, e.g. WithReport reports the coverage of the drift.
+func ReadDriftedAssets(ctx context.Context, path, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	return ReadMergedDriftedAssets(ctx, []string{path}, project, ancestry, offline, opts...)
+}
 
-	data, err := readTF12Data(path)
+// ReadMergedDriftedAssets is like ReadDriftedAssets for several plan files,
+// see ReadMergedPlannedAssets.
+func ReadMergedDriftedAssets(ctx context.Context, paths []string, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	plans, err := ReadPlanFiles(paths)
 	if err != nil {
 		return nil, err
 	}
+	return ConvertDriftedAssets(ctx, plans, project, ancestry, offline, opts...)
+}
 
-	changes, err := tfplan.ReadResourceChanges(data)
-	if err != nil {
-		return nil, errors.Wrap(err, "reading resource changes")
+// PlanFile is a terraform plan read from a JSON plan file by ReadPlanFiles.
+type PlanFile struct {
+	// Path is the plan file, which converted assets list in SourcePlans.
+	Path string
+	Plan *tfplan.Plan
+}
+
+// ReadPlanFiles reads and parses terraform JSON plan files, so that both
+// their resource changes (see ConvertPlannedAssets) and their resource drift
+// (see ConvertDriftedAssets) can be converted without reading them twice.
+func ReadPlanFiles(paths []string) ([]PlanFile, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no plan files given")
 	}
+	plans := make([]PlanFile, 0, len(paths))
+	for _, path := range paths {
+		data, err := readTF12Data(path)
+		if err != nil {
+			return nil, err
+		}
+		plan, err := tfplan.ReadPlan(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading resource changes from %s", path)
+		}
+		plans = append(plans, PlanFile{Path: path, Plan: plan})
+	}
+	return plans, nil
+}
+
+// ConvertPlannedAssets is like ReadMergedPlannedAssets for plans read by
+// ReadPlanFiles.
+func ConvertPlannedAssets(ctx context.Context, plans []PlanFile, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	return convertAssets(ctx, plans, project, ancestry, offline, resourceChanges, opts)
+}
+
+// ConvertDriftedAssets is like ReadMergedDriftedAssets for plans read by
+// ReadPlanFiles.
+func ConvertDriftedAssets(ctx context.Context, plans []PlanFile, project, ancestry string, offline bool, opts ...Option) ([]google.Asset, error) {
+	return convertAssets(ctx, plans, project, ancestry, offline, resourceDrift, opts)
+}
+
+// selectChangesFunc selects the resource changes of a plan to be converted.
+type selectChangesFunc func(plan *tfplan.Plan) []*tfjson.ResourceChange
+
+func resourceChanges(plan *tfplan.Plan) []*tfjson.ResourceChange { return plan.ResourceChanges }
+func resourceDrift(plan *tfplan.Plan) []*tfjson.ResourceChange   { return plan.ResourceDrift }
 
-	err = converter.AddResourceChanges(changes)
+func convertAssets(ctx context.Context, plans []PlanFile, project, ancestry string, offline bool, selectChanges selectChangesFunc, opts []Option) ([]google.Asset, error) {
+	if len(plans) == 0 {
+		return nil, errors.New("no plan files given")
+	}
+	o := newReadOptions(opts)
+	if err := o.filter.Validate(); err != nil {
+		return nil, err
+	}
+	converter, err := newConverter(ctx, project, ancestry, offline)
 	if err != nil {
-		return nil, errors.Wrap(err, "adding resource changes to converter")
+		return nil, err
+	}
+	converter.SetContinueOnError(o.continueOnError)
+	converter.SetDeletions(o.deletions)
+	if o.stream != nil {
+		converter.SetStream(o.stream)
+	}
+	// The clock is read once, so that the assets of every plan share the
+	// same update time.
+	now := time.Now()
+	if !o.timestamp.IsZero() {
+		now = o.timestamp
+	}
+	if o.providerSchema != "" {
+		data, err := ioutil.ReadFile(o.providerSchema)
+		if err != nil {
+			return nil, errors.Wrap(err, "opening provider schema file")
+		}
+		schemas, err := tfplan.ReadProviderSchemas(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading provider schemas from %s", o.providerSchema)
+		}
+		converter.SetProviderSchemas(schemas)
+	}
+	if o.mappingsDir != "" {
+		mappings, err := google.LoadMappings(o.mappingsDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := converter.AddMappings(mappings); err != nil {
+			return nil, err
+		}
+	}
+	if o.pluginsDir != "" {
+		plugins, err := google.LoadPlugins(o.pluginsDir, o.pluginTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if err := converter.AddPlugins(plugins); err != nil {
+			return nil, err
+		}
+	}
+	if o.transformsFile != "" {
+		transformers, err := google.LoadTransforms(o.transformsFile)
+		if err != nil {
+			return nil, err
+		}
+		converter.AddTransformers(transformers...)
+	}
+	converter.AddTransformers(o.transformers...)
+
+	for _, p := range plans {
+		path, plan := p.Path, p.Plan
+		changes, filtered := o.filter.Apply(selectChanges(plan))
+		if o.report != nil {
+			for _, f := range filtered {
+				o.report.Filtered = append(o.report.Filtered, FilteredResource{FilteredResource: f, SourcePlan: path})
+			}
+		}
+
+		if o.planTimestamp && !plan.Timestamp.IsZero() {
+			converter.SetClock(fixedClock(plan.Timestamp))
+		} else {
+			converter.SetClock(fixedClock(now))
+		}
+		converter.SetProviderDefaults(plan.ProviderConfigValue("google", "region"), plan.ProviderConfigValue("google", "zone"))
+		converter.AddPlanMoves(path, plan.PreviousAddresses)
+		err = converter.AddPlanResourceChanges(path, changes)
+		if o.report != nil {
+			o.report.Coverage = converter.Coverage()
+			o.report.SchemaDrift = converter.SchemaDrift()
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "adding resource changes from %s to converter", path)
+		}
 	}
 
+	if errs := converter.Errors(); len(errs) > 0 {
+		if o.report != nil {
+			o.report.Errors = errs
+		}
+		return converter.Assets(), &IncompleteError{Errors: errs}
+	}
 	return converter.Assets(), nil
 }
 
-func newConverter(ctx context.Context, path, project, ancestry string, offline bool) (*google.Converter, error) {
+// fixedClock returns a clock that always returns t.
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func newConverter(ctx context.Context, project, ancestry string, offline bool) (*google.Converter, error) {
 	ua := option.WithUserAgent(fmt.Sprintf("config-validator-tf/%s", BuildVersion()))
 	ancestryManager, err := ancestrymanager.New(context.Background(), project, ancestry, offline, ua)
 	if err != nil {
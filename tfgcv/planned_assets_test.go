@@ -2,8 +2,14 @@ package tfgcv
 
 import (
 	"context"
+	"errors"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
 )
 
 const (
@@ -74,3 +80,301 @@ func TestReadPlannedAssets(t *testing.T) {
 		})
 	}
 }
+
+func TestReadDriftedAssets(t *testing.T) {
+	cases := []struct {
+		name string
+		file string
+		want int
+	}{
+		{
+			name: "Plan without drift",
+			file: "tf1_0plan.json",
+			want: 0,
+		},
+		{
+			name: "Plan with drift",
+			file: "tf1_0plan.drift.json",
+			want: 1,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			testFile := filepath.Join(testDataDir, c.file)
+			ctx := context.Background()
+			got, err := ReadDriftedAssets(ctx, testFile, testProjectName, testAncestryName, true)
+			if err != nil {
+				t.Fatalf("ReadDriftedAssets() error = %v", err)
+			}
+			if len(got) != c.want {
+				t.Errorf("ReadDriftedAssets() = %v, want %v", len(got), c.want)
+			}
+		})
+	}
+}
+
+func TestConvertDriftedAssets(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.drift.json")
+	ctx := context.Background()
+	plans, err := ReadPlanFiles([]string{testFile})
+	if err != nil {
+		t.Fatalf("ReadPlanFiles() error = %v", err)
+	}
+	planned, err := ConvertPlannedAssets(ctx, plans, testProjectName, testAncestryName, true)
+	if err != nil {
+		t.Fatalf("ConvertPlannedAssets() error = %v", err)
+	}
+	// The resource drift is reported like the resource changes, e.g. for
+	// --strict.
+	report := &Report{}
+	drifted, err := ConvertDriftedAssets(ctx, plans, testProjectName, testAncestryName, true, WithReport(report))
+	if err != nil {
+		t.Fatalf("ConvertDriftedAssets() error = %v", err)
+	}
+	if len(report.Coverage) != 1 || len(report.Uncovered()) != 0 {
+		t.Errorf("ConvertDriftedAssets() coverage = %+v, want 1 converted resource", report.Coverage)
+	}
+	want, err := ReadPlannedAssets(ctx, testFile, testProjectName, testAncestryName, true)
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(planned) != len(want) {
+		t.Errorf("ConvertPlannedAssets() = %v assets, want %v", len(planned), len(want))
+	}
+	if len(drifted) != 1 {
+		t.Errorf("ConvertDriftedAssets() = %v assets, want 1", len(drifted))
+	}
+	for _, a := range drifted {
+		if !reflect.DeepEqual(a.SourcePlans, []string{testFile}) {
+			t.Errorf("%s: SourcePlans = %v, want %v", a.Name, a.SourcePlans, []string{testFile})
+		}
+	}
+
+	if _, err := ReadPlanFiles(nil); err == nil {
+		t.Error("ReadPlanFiles(nil) succeeded, want error")
+	}
+}
+
+func TestReadMergedPlannedAssets(t *testing.T) {
+	files := []string{
+		filepath.Join(testDataDir, "tf1_0plan.json"),
+		filepath.Join(testDataDir, "tf0_12plan.json"),
+	}
+	ctx := context.Background()
+	got, err := ReadMergedPlannedAssets(ctx, files, testProjectName, testAncestryName, true)
+	if err != nil {
+		t.Fatalf("ReadMergedPlannedAssets() error = %v", err)
+	}
+	// Both plans create the same two firewalls.
+	if len(got) != 2 {
+		t.Errorf("ReadMergedPlannedAssets() = %v, want %v", len(got), 2)
+	}
+	plans := SourcePlansByAssetName(got)
+	for _, a := range got {
+		if len(plans[a.Name]) == 0 {
+			t.Errorf("asset %s has no source plan", a.Name)
+		}
+	}
+}
+
+func TestReadPlannedAssets_filter(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	ctx := context.Background()
+	report := &Report{}
+	got, err := ReadPlannedAssets(ctx, testFile, testProjectName, testAncestryName, true,
+		WithInclude("module.mymodule.*", "google_compute_firewall"),
+		WithExclude("module.mymodule.*"),
+		WithReport(report),
+	)
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(got) != 1 {
+		t.Errorf("ReadPlannedAssets() = %v, want %v", len(got), 1)
+	}
+	want := []FilteredResource{
+		{
+			FilteredResource: tfplan.FilteredResource{
+				Address:  "module.mymodule.google_compute_firewall.http",
+				Type:     "google_compute_firewall",
+				Provider: "google",
+				Reason:   `excluded by pattern "module.mymodule.*"`,
+			},
+			SourcePlan: testFile,
+		},
+	}
+	if !reflect.DeepEqual(report.Filtered, want) {
+		t.Errorf("report.Filtered = %+v, want %+v", report.Filtered, want)
+	}
+}
+
+func TestReadPlannedAssets_invalidFilter(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	_, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithExclude(`net\`))
+	if err == nil {
+		t.Fatal("ReadPlannedAssets() error = nil, want invalid pattern error")
+	}
+}
+
+func TestReadPlannedAssets_coverage(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	report := &Report{}
+	_, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithReport(report))
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(report.Coverage) != 2 {
+		t.Fatalf("len(report.Coverage) = %v, want %v", len(report.Coverage), 2)
+	}
+	for _, c := range report.Coverage {
+		if c.Status != google.CoverageConverted || len(c.AssetNames) != 1 {
+			t.Errorf("coverage of %s = %+v, want converted into one asset", c.Address, c)
+		}
+	}
+	if uncovered := report.Uncovered(); len(uncovered) != 0 {
+		t.Errorf("report.Uncovered() = %+v, want none", uncovered)
+	}
+}
+
+func TestReadPlannedAssets_continueOnError(t *testing.T) {
+	// One firewall sets its project, whose ancestry cannot be found offline;
+	// the other one uses the provider project.
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	report := &Report{}
+	got, err := ReadPlannedAssets(context.Background(), testFile, "other-project", testAncestryName, true, WithContinueOnError(), WithReport(report))
+	incomplete, ok := err.(*IncompleteError)
+	if !ok {
+		t.Fatalf("ReadPlannedAssets() error = %v, want *IncompleteError", err)
+	}
+	if len(got) != 1 || len(incomplete.Errors) != 1 {
+		t.Errorf("ReadPlannedAssets() = %v assets and %v errors, want 1 and 1", len(got), len(incomplete.Errors))
+	}
+	if len(report.Errors) != 1 {
+		t.Errorf("len(report.Errors) = %v, want 1", len(report.Errors))
+	}
+}
+
+func TestReadPlannedAssets_providerSchema(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	schemaFile := filepath.Join(testDataDir, "provider_schema.json")
+	report := &Report{}
+	got, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithProviderSchemaFile(schemaFile), WithReport(report))
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("len(ReadPlannedAssets()) = %v, want %v", len(got), 2)
+	}
+	if len(report.SchemaDrift) != 0 {
+		t.Errorf("report.SchemaDrift = %+v, want none", report.SchemaDrift)
+	}
+
+	_, err = ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithProviderSchemaFile(filepath.Join(testDataDir, "missing.json")))
+	if err == nil {
+		t.Error("ReadPlannedAssets() with a missing provider schema file succeeded, want error")
+	}
+}
+
+func TestReadPlannedAssets_transformers(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.json")
+	transformsFile := filepath.Join(testDataDir, "transforms.yaml")
+	var seen []string
+	record := google.TransformerFunc(func(asset *google.Asset) error {
+		seen = append(seen, asset.Name)
+		return nil
+	})
+	got, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithTransformsFile(transformsFile), WithTransformers(record))
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(seen) != len(got) {
+		t.Errorf("transformer ran on %d assets, want %d", len(seen), len(got))
+	}
+	for _, asset := range got {
+		if d := asset.Resource.Data["description"]; d != "managed by terraform" {
+			t.Errorf("%s: description = %v, want %q", asset.Name, d, "managed by terraform")
+		}
+		if p, ok := asset.Resource.Data["priority"]; ok {
+			t.Errorf("%s: priority = %v, want deleted", asset.Name, p)
+		}
+	}
+
+	fail := google.TransformerFunc(func(asset *google.Asset) error {
+		return errors.New("failed")
+	})
+	_, err = ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithTransformers(fail))
+	if err == nil {
+		t.Error("ReadPlannedAssets() with a failing transformer succeeded, want error")
+	}
+}
+
+func TestReadPlannedAssets_timestamp(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_5plan.orgpolicy.json")
+	fixed := time.Date(2021, time.April, 14, 15, 16, 17, 0, time.UTC)
+	planTime := time.Date(2023, time.June, 12, 9, 30, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		opts []Option
+		want time.Time
+	}{
+		{"Timestamp", []Option{WithTimestamp(fixed)}, fixed},
+		{"PlanTimestamp", []Option{WithTimestamp(fixed), WithPlanTimestamp()}, planTime},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, c.opts...)
+			if err != nil {
+				t.Fatalf("ReadPlannedAssets() error = %v", err)
+			}
+			if len(got) != 1 || len(got[0].OrgPolicy) != 1 {
+				t.Fatalf("ReadPlannedAssets() = %v, want 1 asset with 1 org policy", got)
+			}
+			want := &google.Timestamp{Seconds: c.want.Unix(), Nanos: c.want.UnixNano()}
+			if updateTime := got[0].OrgPolicy[0].UpdateTime; !reflect.DeepEqual(updateTime, want) {
+				t.Errorf("update time = %v, want %v", updateTime, want)
+			}
+		})
+	}
+}
+
+func TestReadPlannedAssets_deletions(t *testing.T) {
+	testFile := filepath.Join(testDataDir, "tf1_0plan.deletion.json")
+	report := &Report{}
+	got, err := ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithReport(report))
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	// Only the replacement bucket.
+	if len(got) != 1 || got[0].Deleted {
+		t.Errorf("ReadPlannedAssets() = %+v, want the replacement bucket only", got)
+	}
+	var deletions []string
+	for _, d := range report.Deletions() {
+		deletions = append(deletions, d.Address+" "+d.Action)
+	}
+	want := []string{"google_kms_key_ring.prod delete", "google_storage_bucket.logs delete-create"}
+	if !reflect.DeepEqual(deletions, want) {
+		t.Errorf("report.Deletions() = %v, want %v", deletions, want)
+	}
+
+	got, err = ReadPlannedAssets(context.Background(), testFile, testProjectName, testAncestryName, true, WithDeletions())
+	if err != nil {
+		t.Fatalf("ReadPlannedAssets() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("len(ReadPlannedAssets()) = %v, want %v", len(got), 3)
+	}
+	keyRing := got[0]
+	if keyRing.Type != "kms.googleapis.com/KeyRing" || !keyRing.Deleted {
+		t.Errorf("ReadPlannedAssets()[0] = %+v, want a deleted key ring", keyRing)
+	}
+	if keyRing.Resource == nil || keyRing.Resource.Location != "europe-west1" {
+		t.Errorf("key ring resource = %+v, want location europe-west1", keyRing.Resource)
+	}
+	if got[1].Deleted {
+		t.Errorf("ReadPlannedAssets()[1] = %+v, want the replacement bucket", got[1])
+	}
+	if got[2].Name != got[1].Name || !got[2].Deleted {
+		t.Errorf("ReadPlannedAssets()[2] = %+v, want the tombstone of the replaced bucket", got[2])
+	}
+}
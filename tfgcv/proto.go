@@ -17,6 +17,8 @@ package tfgcv
 import (
 	"encoding/json"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/forseti-security/config-validator/pkg/api/validator"
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/golang/protobuf/proto"
 	"github.com/pkg/errors"
@@ -36,3 +38,26 @@ func protoViaJSON(from interface{}, to proto.Message) error {
 
 	return nil
 }
+
+// AssetToProto converts an asset into the asset message of GCV. The fields
+// that the message has no room for (the audit configs of the IAM policy, v2
+// org policies, the update time and the deletion marker) are left out.
+// Ancestors are left out as well: GCV replaces the ancestry path with them
+// when they are set, and they leave out nodes of the ancestry path (e.g.
+// unknown organizations), which would change what constraints match.
+func AssetToProto(asset google.Asset) (*validator.Asset, error) {
+	if asset.IAMPolicy != nil && len(asset.IAMPolicy.AuditConfigs) > 0 {
+		policy := *asset.IAMPolicy
+		policy.AuditConfigs = nil
+		asset.IAMPolicy = &policy
+	}
+	asset.V2OrgPolicies = nil
+	asset.UpdateTime = nil
+	asset.Deleted = false
+	asset.Ancestors = nil
+	pb := &validator.Asset{}
+	if err := protoViaJSON(asset, pb); err != nil {
+		return nil, errors.Wrapf(err, "converting asset %s to proto", asset.Name)
+	}
+	return pb, nil
+}
@@ -17,11 +17,15 @@ package tfgcv
 import (
 	"testing"
 
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/forseti-security/config-validator/pkg/api/validator"
 	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/genproto/googleapis/cloud/asset/v1"
+	iam "google.golang.org/genproto/googleapis/iam/v1"
+	accesscontextmanager "google.golang.org/genproto/googleapis/identity/accesscontextmanager/v1"
+	"google.golang.org/genproto/googleapis/type/expr"
 )
 
 func TestProtoViaJSON(t *testing.T) {
@@ -85,6 +89,66 @@ func TestProtoViaJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "ConditionalIAMBinding",
+			input: google.Asset{
+				IAMPolicy: &google.IAMPolicy{
+					Bindings: []google.IAMBinding{{
+						Role:    "roles/viewer",
+						Members: []string{"user:alice@example.com"},
+						Condition: &google.IAMCondition{
+							Title:      "expires",
+							Expression: `request.time < timestamp("2022-01-01T00:00:00Z")`,
+						},
+					}},
+				},
+			},
+			expected: &validator.Asset{
+				IamPolicy: &iam.Policy{
+					Bindings: []*iam.Binding{{
+						Role:    "roles/viewer",
+						Members: []string{"user:alice@example.com"},
+						Condition: &expr.Expr{
+							Title:      "expires",
+							Expression: `request.time < timestamp("2022-01-01T00:00:00Z")`,
+						},
+					}},
+				},
+			},
+		},
+		{
+			name: "ServicePerimeter",
+			input: google.Asset{
+				ServicePerimeter: &google.ServicePerimeter{
+					Name:          "accessPolicies/123/servicePerimeters/restrict_storage",
+					PerimeterType: "PERIMETER_TYPE_REGULAR",
+					Status: &google.ServicePerimeterConfig{
+						Resources:          []string{"projects/456"},
+						RestrictedServices: []string{"storage.googleapis.com"},
+						VpcAccessibleServices: &google.VpcAccessibleServices{
+							EnableRestriction: true,
+							AllowedServices:   []string{"RESTRICTED-SERVICES"},
+						},
+					},
+				},
+			},
+			expected: &validator.Asset{
+				AccessContextPolicy: &validator.Asset_ServicePerimeter{
+					ServicePerimeter: &accesscontextmanager.ServicePerimeter{
+						Name:          "accessPolicies/123/servicePerimeters/restrict_storage",
+						PerimeterType: accesscontextmanager.ServicePerimeter_PERIMETER_TYPE_REGULAR,
+						Status: &accesscontextmanager.ServicePerimeterConfig{
+							Resources:          []string{"projects/456"},
+							RestrictedServices: []string{"storage.googleapis.com"},
+							VpcAccessibleServices: &accesscontextmanager.ServicePerimeterConfig_VpcAccessibleServices{
+								EnableRestriction: true,
+								AllowedServices:   []string{"RESTRICTED-SERVICES"},
+							},
+						},
+					},
+				},
+			},
+		},
 	}
 
 	for _, c := range cases {
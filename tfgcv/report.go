@@ -0,0 +1,90 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+	"github.com/GoogleCloudPlatform/terraform-validator/tfplan"
+)
+
+// Report describes what happened to the resource changes of the plans read
+// with the WithReport option.
+type Report struct {
+	// Filtered lists the resource changes dropped by WithInclude and
+	// WithExclude.
+	Filtered []FilteredResource `json:"filtered,omitempty"`
+	// Coverage lists the outcome of converting each resource change that
+	// was not filtered out.
+	Coverage []google.ResourceCoverage `json:"coverage,omitempty"`
+	// Errors lists the resource changes that failed to convert with
+	// WithContinueOnError.
+	Errors []*google.ResourceError `json:"errors,omitempty"`
+	// SchemaDrift lists the attributes in the plans that are unknown to the
+	// conversion schema and were therefore not converted.
+	SchemaDrift []google.SchemaDrift `json:"schema_drift,omitempty"`
+}
+
+// Uncovered lists the google resources that could not be converted, and
+// were therefore not validated.
+func (r *Report) Uncovered() []google.ResourceCoverage {
+	var uncovered []google.ResourceCoverage
+	for _, c := range r.Coverage {
+		if c.IsUncovered() {
+			uncovered = append(uncovered, c)
+		}
+	}
+	return uncovered
+}
+
+// Deletions lists the resource changes that delete a resource, including
+// replacements, whether or not they were converted.
+func (r *Report) Deletions() []google.ResourceCoverage {
+	var deletions []google.ResourceCoverage
+	for _, c := range r.Coverage {
+		switch c.Action {
+		case tfplan.ActionDelete.String(), tfplan.ActionDeleteCreate.String(), tfplan.ActionCreateDelete.String():
+			deletions = append(deletions, c)
+		}
+	}
+	return deletions
+}
+
+// CoverageCounts counts the resource changes by coverage status.
+func (r *Report) CoverageCounts() map[google.CoverageStatus]int {
+	counts := make(map[google.CoverageStatus]int)
+	for _, c := range r.Coverage {
+		counts[c.Status]++
+	}
+	return counts
+}
+
+// FilteredResource is a resource change that was not converted because of
+// the include and exclude patterns.
+type FilteredResource struct {
+	tfplan.FilteredResource
+	SourcePlan string `json:"source_plan"`
+}
+
+// IncompleteError is returned together with the converted assets when some
+// resource changes failed to convert with WithContinueOnError.
+type IncompleteError struct {
+	Errors []*google.ResourceError
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%d resource(s) could not be converted", len(e.Errors))
+}
@@ -16,11 +16,14 @@ package tfgcv
 
 import (
 	"context"
+	"encoding/json"
 	"path/filepath"
 
 	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
 	"github.com/forseti-security/config-validator/pkg/api/validator"
+	gcvasset "github.com/forseti-security/config-validator/pkg/asset"
 	"github.com/forseti-security/config-validator/pkg/gcv"
+	structpb "github.com/golang/protobuf/ptypes/struct"
 	"github.com/pkg/errors"
 )
 
@@ -48,28 +51,297 @@ func ValidateAssetsWithLibrary(ctx context.Context, assets []google.Asset, polic
 	}
 
 	pbAssets := make([]*validator.Asset, len(assets))
+	extras := make(map[string]assetExtras)
+	var v2OrgPolicyAssets []*validator.Asset
 	for i := range assets {
-		pbAssets[i] = &validator.Asset{}
-		if err := protoViaJSON(assets[i], pbAssets[i]); err != nil {
-			return nil, errors.Wrapf(err, "converting asset %s to proto", assets[i].Name)
+		asset := assets[i]
+		key := asset.Type + asset.Name
+		pb, err := AssetToProto(asset)
+		if err != nil {
+			return nil, err
+		}
+		pbAssets[i] = pb
+		if asset.IAMPolicy != nil && len(asset.IAMPolicy.AuditConfigs) > 0 {
+			// The IAM policy message of GCV has no audit configs, they
+			// are added back by reviewAsset.
+			e := extras[key]
+			e.auditConfigs = asset.IAMPolicy.AuditConfigs
+			extras[key] = e
+		}
+		if asset.Deleted {
+			e := extras[key]
+			e.deleted = true
+			extras[key] = e
+		}
+		// Neither has the asset message of GCV v2 org policies. They are
+		// reviewed on their own, like the other policies of an asset.
+		if len(assets[i].V2OrgPolicies) > 0 {
+			e := extras[key]
+			e.v2OrgPolicies = assets[i].V2OrgPolicies
+			extras[key] = e
+			v2OrgPolicyAssets = append(v2OrgPolicyAssets, &validator.Asset{
+				Name:         pbAssets[i].Name,
+				AssetType:    pbAssets[i].AssetType,
+				AncestryPath: pbAssets[i].AncestryPath,
+			})
 		}
 	}
 
-	pbSplitAssets := splitAssets(pbAssets)
+	pbSplitAssets := append(splitAssets(pbAssets), v2OrgPolicyAssets...)
 
 	auditResult := &validator.AuditResponse{}
 	for _, asset := range pbSplitAssets {
-		violations, err := valid.ReviewAsset(context.Background(), asset)
+		e := extras[asset.AssetType+asset.Name]
+		violations, err := reviewAsset(context.Background(), valid, asset, e)
 
 		if err != nil {
 			return nil, errors.Wrapf(err, "reviewing asset %s", asset)
 		}
+		if e.deleted {
+			violations = deletionViolations(violations)
+		}
 		auditResult.Violations = append(auditResult.Violations, violations...)
 	}
 
 	return auditResult, nil
 }
 
+// DeletionsAnnotation is the constraint annotation that opts a constraint
+// into reviewing tombstones (see google.Converter.SetDeletions), when set to
+// "true". Other constraints are not written for resources being deleted, and
+// their violations on tombstones are dropped.
+const DeletionsAnnotation = "terraform-validator/deletions"
+
+// deletionViolations returns the violations of the constraints that opt into
+// reviewing tombstones.
+func deletionViolations(violations []*validator.Violation) []*validator.Violation {
+	var kept []*validator.Violation
+	for _, v := range violations {
+		metadata := v.GetConstraintConfig().GetMetadata().GetStructValue()
+		annotations := metadata.GetFields()["annotations"].GetStructValue()
+		if annotations.GetFields()[DeletionsAnnotation].GetStringValue() == "true" {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// ViolationCategoryResourceDrift is the "category" metadata value of
+// violations found on resource drift.
+const ViolationCategoryResourceDrift = "resource_drift"
+
+// ValidateDriftedAssets audits assets read by ReadDriftedAssets using
+// "policies" and "lib" folder under policyRootPath. The returned violations
+// carry a "category" metadata entry set to ViolationCategoryResourceDrift so
+// that they can be reported separately from violations on planned changes.
+func ValidateDriftedAssets(ctx context.Context, assets []google.Asset, policyRootPath string) (*validator.AuditResponse, error) {
+	auditResult, err := ValidateAssets(ctx, assets, policyRootPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range auditResult.Violations {
+		setViolationMetadata(v, "category", &structpb.Value{
+			Kind: &structpb.Value_StringValue{StringValue: ViolationCategoryResourceDrift},
+		})
+	}
+	return auditResult, nil
+}
+
+// ViolationCategoryMoved is the "category" metadata value of violations on
+// moved resources that existed before the move.
+const ViolationCategoryMoved = "moved"
+
+// SplitMovedViolations moves the violations of auditResult that existed
+// before the resources of the violating assets were moved (see
+// google.Asset.Prior) out of auditResult, using "policies" and "lib" folder
+// under policyRootPath. A violation existed before the move if the asset
+// before the move has a violation of the same constraint with the same
+// message. The returned violations carry a "category" metadata entry set to
+// ViolationCategoryMoved so that they are not reported as newly introduced.
+func SplitMovedViolations(ctx context.Context, auditResult *validator.AuditResponse, assets []google.Asset, policyRootPath string) (*validator.AuditResponse, error) {
+	moved := &validator.AuditResponse{}
+	var priors []google.Asset
+	for _, a := range assets {
+		if a.Prior != nil {
+			priors = append(priors, *a.Prior)
+		}
+	}
+	if len(priors) == 0 {
+		return moved, nil
+	}
+	priorResult, err := ValidateAssets(ctx, priors, policyRootPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "validating assets before the move")
+	}
+	existed := make(map[string]bool)
+	for _, v := range priorResult.Violations {
+		existed[violationKey(v)] = true
+	}
+
+	var introduced []*validator.Violation
+	for _, v := range auditResult.Violations {
+		if !existed[violationKey(v)] {
+			introduced = append(introduced, v)
+			continue
+		}
+		setViolationMetadata(v, "category", &structpb.Value{
+			Kind: &structpb.Value_StringValue{StringValue: ViolationCategoryMoved},
+		})
+		moved.Violations = append(moved.Violations, v)
+	}
+	auditResult.Violations = introduced
+	return moved, nil
+}
+
+// violationKey identifies a violation of a constraint by a resource.
+func violationKey(v *validator.Violation) string {
+	return v.Constraint + "\n" + v.Resource + "\n" + v.Message
+}
+
+// SetViolationSourcePlans adds a "source_plans" metadata entry to each
+// violation, listing the plans that contributed to the violating asset.
+// Violations on assets without source plans are left unchanged.
+func SetViolationSourcePlans(auditResult *validator.AuditResponse, assets []google.Asset) {
+	plans := SourcePlansByAssetName(assets)
+	for _, v := range auditResult.Violations {
+		sources, ok := plans[v.Resource]
+		if !ok {
+			continue
+		}
+		values := make([]*structpb.Value, 0, len(sources))
+		for _, p := range sources {
+			values = append(values, &structpb.Value{Kind: &structpb.Value_StringValue{StringValue: p}})
+		}
+		setViolationMetadata(v, "source_plans", &structpb.Value{
+			Kind: &structpb.Value_ListValue{ListValue: &structpb.ListValue{Values: values}},
+		})
+	}
+}
+
+// SourcePlansByAssetName maps asset names to the plans that contributed to
+// them.
+func SourcePlansByAssetName(assets []google.Asset) map[string][]string {
+	plans := make(map[string][]string)
+	for _, a := range assets {
+		for _, p := range a.SourcePlans {
+			if !containsString(plans[a.Name], p) {
+				plans[a.Name] = append(plans[a.Name], p)
+			}
+		}
+	}
+	return plans
+}
+
+// SetViolationProvenance adds a "provenance" metadata entry to each
+// violation, listing the terraform resource changes that contributed to the
+// violating asset (see google.Provenance).
+func SetViolationProvenance(auditResult *validator.AuditResponse, assets []google.Asset) error {
+	provenance := ProvenanceByAssetName(assets)
+	for _, v := range auditResult.Violations {
+		prov, ok := provenance[v.Resource]
+		if !ok {
+			continue
+		}
+		value := &structpb.Value{}
+		if err := protoViaJSON(prov, value); err != nil {
+			return errors.Wrapf(err, "converting provenance of %s", v.Resource)
+		}
+		setViolationMetadata(v, "provenance", value)
+	}
+	return nil
+}
+
+// ProvenanceByAssetName maps asset names to the terraform resource changes
+// that contributed to them. The entries of the same resource in the same plan
+// (e.g. for assets of different types with the same name) are merged into
+// one, and the entries of the same resource in several plans are kept apart.
+func ProvenanceByAssetName(assets []google.Asset) map[string][]google.Provenance {
+	provenance := make(map[string][]google.Provenance)
+	for _, a := range assets {
+		for _, p := range a.Provenance {
+			provenance[a.Name] = addProvenance(provenance[a.Name], p)
+		}
+	}
+	return provenance
+}
+
+// addProvenance adds p to the entries of an asset, merging it with the entry
+// of the same plan and resource address if there is one.
+func addProvenance(entries []google.Provenance, p google.Provenance) []google.Provenance {
+	for i, e := range entries {
+		if e.SourcePlan != p.SourcePlan || e.Address != p.Address {
+			continue
+		}
+		for _, f := range p.Fields {
+			if !containsString(e.Fields, f) {
+				e.Fields = append(e.Fields, f)
+			}
+		}
+		for _, f := range p.Removed {
+			if !containsString(e.Removed, f) {
+				e.Removed = append(e.Removed, f)
+			}
+		}
+		entries[i] = e
+		return entries
+	}
+	p.Fields = append([]string(nil), p.Fields...)
+	p.Removed = append([]string(nil), p.Removed...)
+	return append(entries, p)
+}
+
+// SetViolationLocation adds a "location" metadata entry to each violation,
+// the location of the violating asset's resource (see
+// google.AssetResource.Location).
+func SetViolationLocation(auditResult *validator.AuditResponse, assets []google.Asset) {
+	locations := LocationsByAssetName(assets)
+	for _, v := range auditResult.Violations {
+		location, ok := locations[v.Resource]
+		if !ok {
+			continue
+		}
+		setViolationMetadata(v, "location", &structpb.Value{
+			Kind: &structpb.Value_StringValue{StringValue: location},
+		})
+	}
+}
+
+// LocationsByAssetName maps the names of assets with a resource to the
+// location of the resource.
+func LocationsByAssetName(assets []google.Asset) map[string]string {
+	locations := make(map[string]string)
+	for _, a := range assets {
+		if a.Resource != nil && a.Resource.Location != "" {
+			locations[a.Name] = a.Resource.Location
+		}
+	}
+	return locations
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// setViolationMetadata sets key in the metadata struct of a violation,
+// creating the struct if needed.
+func setViolationMetadata(v *validator.Violation, key string, value *structpb.Value) {
+	if v.Metadata.GetStructValue() == nil {
+		v.Metadata = &structpb.Value{
+			Kind: &structpb.Value_StructValue{StructValue: &structpb.Struct{}},
+		}
+	}
+	s := v.Metadata.GetStructValue()
+	if s.Fields == nil {
+		s.Fields = map[string]*structpb.Value{}
+	}
+	s.Fields[key] = value
+}
+
 // splitAssets split assets because for the GCP target Constraint
 // Framework ReviewAsset call an asset must have only one of:
 // resource, iam policy, org policy or access context policy
@@ -109,3 +381,91 @@ func splitAssets(pbAssets []*validator.Asset) []*validator.Asset {
 	}
 	return pbSplitAssets
 }
+
+// assetExtras holds the fields of an asset that validator.Asset cannot hold.
+type assetExtras struct {
+	auditConfigs  []google.AuditConfig
+	v2OrgPolicies []*google.V2OrgPolicy
+	deleted       bool
+}
+
+// reviewAsset reviews an asset with GCV. The fields validator.Asset cannot
+// hold are added to the asset the constraints are evaluated on: the audit
+// configs of its IAM policy as iam_policy.audit_configs, its v2 org policies
+// as v2_org_policies on an asset with an empty org_policy and no other policy
+// or resource, and the deletion marker of tombstones as deleted.
+func reviewAsset(ctx context.Context, valid *gcv.Validator, asset *validator.Asset, extras assetExtras) ([]*validator.Violation, error) {
+	auditConfigs := extras.auditConfigs
+	if asset.IamPolicy == nil {
+		auditConfigs = nil
+	}
+	v2OrgPolicies := extras.v2OrgPolicies
+	hasPolicy := asset.Resource != nil || asset.IamPolicy != nil || asset.OrgPolicy != nil || asset.AccessContextPolicy != nil
+	if hasPolicy {
+		v2OrgPolicies = nil
+	}
+	if len(auditConfigs) == 0 && len(v2OrgPolicies) == 0 && !extras.deleted {
+		return valid.ReviewAsset(ctx, asset)
+	}
+
+	// Same as valid.ReviewAsset, with the extra fields added to the JSON
+	// representation of the asset.
+	if hasPolicy {
+		if err := gcvasset.ValidateAsset(asset); err != nil {
+			return nil, err
+		}
+	}
+	if err := gcvasset.SanitizeAncestryPath(asset); err != nil {
+		return nil, err
+	}
+	input, err := gcvasset.ConvertResourceViaJSONToInterface(asset)
+	if err != nil {
+		return nil, err
+	}
+	inputMap := input.(map[string]interface{})
+	if len(auditConfigs) > 0 {
+		policy, ok := inputMap["iam_policy"].(map[string]interface{})
+		if !ok {
+			policy = make(map[string]interface{})
+			inputMap["iam_policy"] = policy
+		}
+		configs, err := jsonInterface(auditConfigs)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting audit configs")
+		}
+		policy["audit_configs"] = configs
+	}
+	if len(v2OrgPolicies) > 0 {
+		policies, err := jsonInterface(v2OrgPolicies)
+		if err != nil {
+			return nil, errors.Wrap(err, "converting v2 org policies")
+		}
+		inputMap["v2_org_policies"] = policies
+		// GCV only reviews assets with a resource or policy: v2 org
+		// policies are reviewed as an asset without v1 org policies.
+		inputMap["org_policy"] = []interface{}{}
+	}
+	if extras.deleted {
+		inputMap["deleted"] = true
+	}
+
+	result, err := valid.ReviewUnmarshalledJSON(ctx, inputMap)
+	if err != nil {
+		return nil, err
+	}
+	return result.ToViolations()
+}
+
+// jsonInterface returns the JSON representation of v as maps, lists and
+// values.
+func jsonInterface(v interface{}) (interface{}, error) {
+	jsn, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(jsn, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,313 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tfgcv
+
+import (
+	"context"
+	"testing"
+
+	"github.com/forseti-security/config-validator/pkg/api/validator"
+	"github.com/golang/protobuf/jsonpb"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/stretchr/testify/require"
+
+	"github.com/GoogleCloudPlatform/terraform-validator/converters/google"
+)
+
+func TestSetViolationMetadata(t *testing.T) {
+	assets := []google.Asset{
+		{
+			Name:        "//cloudresourcemanager.googleapis.com/projects/foo",
+			SourcePlans: []string{"iam.json"},
+			Provenance: []google.Provenance{
+				{
+					Address:    "google_project_iam_member.owner",
+					Type:       "google_project_iam_member",
+					Provider:   "google",
+					Action:     "create",
+					SourcePlan: "iam.json",
+					Fields:     []string{`iam_policy.bindings["roles/owner"].members["user:jane@example.com"]`},
+				},
+			},
+		},
+	}
+	auditResult := &validator.AuditResponse{
+		Violations: []*validator.Violation{
+			{Constraint: "always_violates", Resource: "//cloudresourcemanager.googleapis.com/projects/foo"},
+			{Constraint: "always_violates", Resource: "//cloudresourcemanager.googleapis.com/projects/bar"},
+		},
+	}
+
+	SetViolationSourcePlans(auditResult, assets)
+	if err := SetViolationProvenance(auditResult, assets); err != nil {
+		t.Fatalf("SetViolationProvenance() error = %v", err)
+	}
+
+	got, err := (&jsonpb.Marshaler{}).MarshalToString(auditResult)
+	if err != nil {
+		t.Fatalf("marshaling: %v", err)
+	}
+	want := `{
+	"violations": [
+		{
+			"constraint": "always_violates",
+			"resource": "//cloudresourcemanager.googleapis.com/projects/foo",
+			"metadata": {
+				"source_plans": ["iam.json"],
+				"provenance": [
+					{
+						"address": "google_project_iam_member.owner",
+						"type": "google_project_iam_member",
+						"provider": "google",
+						"action": "create",
+						"source_plan": "iam.json",
+						"fields": ["iam_policy.bindings[\"roles/owner\"].members[\"user:jane@example.com\"]"]
+					}
+				]
+			}
+		},
+		{
+			"constraint": "always_violates",
+			"resource": "//cloudresourcemanager.googleapis.com/projects/bar"
+		}
+	]
+}`
+	require.JSONEq(t, want, got)
+}
+
+func TestProvenanceByAssetName(t *testing.T) {
+	const name = "//cloudresourcemanager.googleapis.com/projects/foo"
+	member := func(plan, field string) google.Provenance {
+		return google.Provenance{Address: "google_project_iam_member.m", Action: "create", SourcePlan: plan, Fields: []string{field}}
+	}
+	assets := []google.Asset{
+		{Name: name, Type: "cloudresourcemanager.googleapis.com/Project", Provenance: []google.Provenance{member("a.json", "a")}},
+		{Name: name, Type: "compute.googleapis.com/Project", Provenance: []google.Provenance{member("a.json", "b")}},
+		{Name: name, Type: "cloudresourcemanager.googleapis.com/Project", Provenance: []google.Provenance{member("b.json", "a")}},
+	}
+	want := map[string][]google.Provenance{
+		name: {
+			{Address: "google_project_iam_member.m", Action: "create", SourcePlan: "a.json", Fields: []string{"a", "b"}},
+			member("b.json", "a"),
+		},
+	}
+	require.Equal(t, want, ProvenanceByAssetName(assets))
+	// The provenance of the assets is left unchanged.
+	require.Equal(t, []string{"a"}, assets[0].Provenance[0].Fields)
+}
+
+func TestValidateAssets_location(t *testing.T) {
+	assets := []google.Asset{{
+		Name:     "//storage.googleapis.com/my-bucket",
+		Type:     "storage.googleapis.com/Bucket",
+		Ancestry: "organization/12345/project/foo",
+		Resource: &google.AssetResource{
+			Version:  "v1",
+			Data:     map[string]interface{}{"name": "my-bucket"},
+			Location: "eu",
+		},
+	}}
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 1)
+	details := auditResult.Violations[0].Metadata.GetStructValue().Fields["details"].GetStructValue()
+	resource := details.Fields["asset"].GetStructValue().Fields["resource"].GetStructValue()
+	require.Equal(t, "eu", resource.Fields["location"].GetStringValue())
+
+	SetViolationLocation(auditResult, assets)
+	require.Equal(t, "eu", auditResult.Violations[0].Metadata.GetStructValue().Fields["location"].GetStringValue())
+}
+
+func TestValidateAssets_auditConfigs(t *testing.T) {
+	assets := []google.Asset{{
+		Name:     "//cloudresourcemanager.googleapis.com/projects/foo",
+		Type:     "cloudresourcemanager.googleapis.com/Project",
+		Ancestry: "organization/12345/project/foo",
+		IAMPolicy: &google.IAMPolicy{
+			AuditConfigs: []google.AuditConfig{{
+				Service: "storage.googleapis.com",
+				AuditLogConfigs: []google.AuditLogConfig{
+					{LogType: "DATA_READ", ExemptedMembers: []string{"user:jane@example.com"}},
+				},
+			}},
+		},
+	}}
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 1)
+
+	// The always_violates constraint reports the asset it was evaluated on.
+	details := auditResult.Violations[0].Metadata.GetStructValue().Fields["details"].GetStructValue()
+	asset := details.Fields["asset"].GetStructValue()
+	configs := asset.Fields["iam_policy"].GetStructValue().Fields["audit_configs"].GetListValue().Values
+	require.Len(t, configs, 1)
+	require.Equal(t, "storage.googleapis.com", configs[0].GetStructValue().Fields["service"].GetStringValue())
+}
+
+func TestValidateAssets_servicePerimeter(t *testing.T) {
+	assets := []google.Asset{{
+		Name:     "//accesscontextmanager.googleapis.com/accessPolicies/123/servicePerimeters/restrict_storage",
+		Type:     "accesscontextmanager.googleapis.com/ServicePerimeter",
+		Ancestry: "organization/12345",
+		ServicePerimeter: &google.ServicePerimeter{
+			Name:          "accessPolicies/123/servicePerimeters/restrict_storage",
+			PerimeterType: "PERIMETER_TYPE_REGULAR",
+			Status: &google.ServicePerimeterConfig{
+				Resources:          []string{"projects/456"},
+				RestrictedServices: []string{"storage.googleapis.com"},
+			},
+		},
+	}}
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 1)
+
+	details := auditResult.Violations[0].Metadata.GetStructValue().Fields["details"].GetStructValue()
+	perimeter := details.Fields["asset"].GetStructValue().Fields["service_perimeter"].GetStructValue()
+	services := perimeter.Fields["status"].GetStructValue().Fields["restricted_services"].GetListValue().Values
+	require.Len(t, services, 1)
+	require.Equal(t, "storage.googleapis.com", services[0].GetStringValue())
+}
+
+func TestValidateAssets_v2OrgPolicies(t *testing.T) {
+	enforce := true
+	assets := []google.Asset{{
+		Name:     "//cloudresourcemanager.googleapis.com/projects/foo",
+		Type:     "cloudresourcemanager.googleapis.com/Project",
+		Ancestry: "organization/12345/project/foo",
+		Resource: &google.AssetResource{
+			Version: "v1",
+			Data:    map[string]interface{}{"projectId": "foo"},
+		},
+		V2OrgPolicies: []*google.V2OrgPolicy{{
+			Name: "projects/foo/policies/iam.disableServiceAccountKeyCreation",
+			Spec: &google.V2OrgPolicySpec{
+				Rules: []google.V2OrgPolicyRule{{
+					Enforce:   &enforce,
+					Condition: &google.Expr{Expression: `resource.matchTag("12345/env", "prod")`},
+				}},
+			},
+		}},
+	}}
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	// The resource and the v2 org policies are reviewed separately.
+	require.Len(t, auditResult.Violations, 2)
+
+	var policies []*structpb.Value
+	for _, v := range auditResult.Violations {
+		details := v.Metadata.GetStructValue().Fields["details"].GetStructValue()
+		asset := details.Fields["asset"].GetStructValue()
+		if p, ok := asset.Fields["v2_org_policies"]; ok {
+			require.Nil(t, asset.Fields["resource"])
+			policies = p.GetListValue().Values
+		}
+	}
+	require.Len(t, policies, 1)
+	rules := policies[0].GetStructValue().Fields["spec"].GetStructValue().Fields["rules"].GetListValue().Values
+	require.Len(t, rules, 1)
+	rule := rules[0].GetStructValue()
+	require.True(t, rule.Fields["enforce"].GetBoolValue())
+	require.Equal(t, `resource.matchTag("12345/env", "prod")`, rule.Fields["condition"].GetStructValue().Fields["expression"].GetStringValue())
+}
+
+func TestValidateAssets_deleted(t *testing.T) {
+	assets := []google.Asset{{
+		Name:     "//storage.googleapis.com/my-bucket",
+		Type:     "storage.googleapis.com/Bucket",
+		Ancestry: "organization/12345/project/foo",
+		Resource: &google.AssetResource{
+			Version: "v1",
+			Data:    map[string]interface{}{"name": "my-bucket"},
+		},
+		Deleted: true,
+	}}
+	// Only the constraints that opt in review tombstones.
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	require.Empty(t, auditResult.Violations)
+
+	auditResult, err = ValidateAssets(context.Background(), assets, "../testdata/sample_policies/deletions")
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 1)
+	require.Contains(t, auditResult.Violations[0].Constraint, "always_violates_deletions")
+
+	// Constraints can target tombstones with input.asset.deleted.
+	details := auditResult.Violations[0].Metadata.GetStructValue().Fields["details"].GetStructValue()
+	asset := details.Fields["asset"].GetStructValue()
+	require.True(t, asset.Fields["deleted"].GetBoolValue())
+	require.Equal(t, "my-bucket", asset.Fields["resource"].GetStructValue().Fields["data"].GetStructValue().Fields["name"].GetStringValue())
+}
+
+func TestValidateAssets_ancestryPath(t *testing.T) {
+	// The ancestors leave out the unknown organization: GCV must still
+	// review the asset with its ancestry path.
+	assets := []google.Asset{{
+		Name:      "//storage.googleapis.com/my-bucket",
+		Type:      "storage.googleapis.com/Bucket",
+		Ancestry:  "organization/unknown/folder/456/project/foobat",
+		Ancestors: []string{"projects/foobat", "folders/456"},
+		Resource: &google.AssetResource{
+			Version: "v1",
+			Data:    map[string]interface{}{"name": "my-bucket"},
+		},
+	}}
+	auditResult, err := ValidateAssets(context.Background(), assets, "../testdata/sample_policies/always_violate")
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 1)
+	details := auditResult.Violations[0].Metadata.GetStructValue().Fields["details"].GetStructValue()
+	asset := details.Fields["asset"].GetStructValue()
+	// GCV pluralizes the collections of the ancestry path.
+	require.Equal(t, "organizations/unknown/folders/456/projects/foobat", asset.Fields["ancestry_path"].GetStringValue())
+	require.Empty(t, asset.Fields["ancestors"].GetListValue().GetValues())
+}
+
+func TestSplitMovedViolations(t *testing.T) {
+	newBucket := func(name, ancestry string) google.Asset {
+		return google.Asset{
+			Name:     "//storage.googleapis.com/" + name,
+			Type:     "storage.googleapis.com/Bucket",
+			Ancestry: ancestry,
+			Resource: &google.AssetResource{
+				Version: "v1",
+				Data:    map[string]interface{}{"name": name},
+			},
+		}
+	}
+	// The constraint only targets organizations: the prior of b is not in
+	// its scope, its violation is introduced by the update.
+	a := newBucket("a", "organization/12345/project/foo")
+	priorA := newBucket("a", "organization/12345/project/foo")
+	a.Prior = &priorA
+	b := newBucket("b", "organization/12345/project/foo")
+	priorB := newBucket("b", "folder/456/project/foo")
+	b.Prior = &priorB
+	c := newBucket("c", "organization/12345/project/foo")
+	assets := []google.Asset{a, b, c}
+
+	policyPath := "../testdata/sample_policies/always_violate"
+	auditResult, err := ValidateAssets(context.Background(), assets, policyPath)
+	require.NoError(t, err)
+	require.Len(t, auditResult.Violations, 3)
+
+	moved, err := SplitMovedViolations(context.Background(), auditResult, assets, policyPath)
+	require.NoError(t, err)
+	require.Len(t, moved.Violations, 1)
+	require.Equal(t, a.Name, moved.Violations[0].Resource)
+	require.Equal(t, ViolationCategoryMoved, moved.Violations[0].Metadata.GetStructValue().Fields["category"].GetStringValue())
+	require.Len(t, auditResult.Violations, 2)
+	require.Equal(t, b.Name, auditResult.Violations[0].Resource)
+	require.Equal(t, c.Name, auditResult.Violations[1].Resource)
+}
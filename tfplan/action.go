@@ -0,0 +1,87 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tfplan
+
+import (
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Action is the action a plan takes on a resource, derived from the list of
+// actions of a resource change.
+type Action int
+
+const (
+	// ActionUnknown is an unrecognized combination of actions.
+	ActionUnknown Action = iota
+	ActionNoOp
+	ActionCreate
+	ActionRead
+	ActionUpdate
+	ActionDelete
+	// ActionDeleteCreate replaces a resource by deleting it first.
+	ActionDeleteCreate
+	// ActionCreateDelete replaces a resource by creating the replacement
+	// first (create_before_destroy).
+	ActionCreateDelete
+)
+
+var actionNames = map[Action]string{
+	ActionUnknown:      "unknown",
+	ActionNoOp:         "no-op",
+	ActionCreate:       "create",
+	ActionRead:         "read",
+	ActionUpdate:       "update",
+	ActionDelete:       "delete",
+	ActionDeleteCreate: "delete-create",
+	ActionCreateDelete: "create-delete",
+}
+
+func (a Action) String() string {
+	return actionNames[a]
+}
+
+// MarshalText encodes the action as its name, e.g. for JSON reports.
+func (a Action) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// IsReplace reports whether the action replaces the resource.
+func (a Action) IsReplace() bool {
+	return a == ActionDeleteCreate || a == ActionCreateDelete
+}
+
+// ActionOf returns the action of a resource change.
+func ActionOf(rc *tfjson.ResourceChange) Action {
+	if rc.Change == nil {
+		return ActionUnknown
+	}
+	actions := rc.Change.Actions
+	switch {
+	case actions.NoOp():
+		return ActionNoOp
+	case actions.Create():
+		return ActionCreate
+	case actions.Read():
+		return ActionRead
+	case actions.Update():
+		return ActionUpdate
+	case actions.Delete():
+		return ActionDelete
+	case actions.DestroyBeforeCreate():
+		return ActionDeleteCreate
+	case actions.CreateBeforeDestroy():
+		return ActionCreateDelete
+	}
+	return ActionUnknown
+}
@@ -0,0 +1,195 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tfplan
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Address is a parsed resource instance address, such as
+// `module.net["eu"].google_compute_subnetwork.default[0]`.
+type Address struct {
+	// Module is the path of module instances containing the resource,
+	// outermost first. It is empty for resources in the root module.
+	Module []ModuleInstance
+	Mode   tfjson.ResourceMode
+	Type   string
+	Name   string
+	// Key is the instance key: nil for a single instance, an int for
+	// resources using count and a string for resources using for_each.
+	Key interface{}
+}
+
+// ModuleInstance is one step of a module path.
+type ModuleInstance struct {
+	Name string
+	// Key is the instance key of the module call, see Address.Key.
+	Key interface{}
+}
+
+// ParseAddress parses a resource instance address as found in the
+// "address" field of a resource change.
+func ParseAddress(s string) (Address, error) {
+	p := &addressParser{s: s}
+	addr := Address{Mode: tfjson.ManagedResourceMode}
+	for p.consume("module.") {
+		name := p.identifier()
+		if name == "" {
+			return Address{}, p.errorf("missing module name")
+		}
+		key, err := p.key()
+		if err != nil {
+			return Address{}, err
+		}
+		addr.Module = append(addr.Module, ModuleInstance{Name: name, Key: key})
+		if !p.consume(".") {
+			return Address{}, p.errorf("missing resource after module")
+		}
+	}
+	if p.consume("data.") {
+		addr.Mode = tfjson.DataResourceMode
+	}
+	addr.Type = p.identifier()
+	if addr.Type == "" || !p.consume(".") {
+		return Address{}, p.errorf("missing resource type")
+	}
+	addr.Name = p.identifier()
+	if addr.Name == "" {
+		return Address{}, p.errorf("missing resource name")
+	}
+	key, err := p.key()
+	if err != nil {
+		return Address{}, err
+	}
+	addr.Key = key
+	if p.pos != len(p.s) {
+		return Address{}, p.errorf("unexpected trailing characters")
+	}
+	return addr, nil
+}
+
+// ModuleAddress returns the address of the module instance containing the
+// resource (e.g. `module.net["eu"]`), or "" for the root module.
+func (a Address) ModuleAddress() string {
+	var parts []string
+	for _, m := range a.Module {
+		parts = append(parts, "module."+m.Name+formatKey(m.Key))
+	}
+	return strings.Join(parts, ".")
+}
+
+// ModulePath returns the names of the module calls leading to the resource,
+// without instance keys. This is the path of the module in the
+// configuration.
+func (a Address) ModulePath() []string {
+	var path []string
+	for _, m := range a.Module {
+		path = append(path, m.Name)
+	}
+	return path
+}
+
+// ConfigAddress returns the address of the resource block within its module
+// configuration (e.g. `google_compute_subnetwork.default`).
+func (a Address) ConfigAddress() string {
+	if a.Mode == tfjson.DataResourceMode {
+		return "data." + a.Type + "." + a.Name
+	}
+	return a.Type + "." + a.Name
+}
+
+func (a Address) String() string {
+	s := a.ConfigAddress() + formatKey(a.Key)
+	if module := a.ModuleAddress(); module != "" {
+		return module + "." + s
+	}
+	return s
+}
+
+func formatKey(key interface{}) string {
+	switch k := key.(type) {
+	case int:
+		return fmt.Sprintf("[%d]", k)
+	case string:
+		return fmt.Sprintf("[%q]", k)
+	}
+	return ""
+}
+
+type addressParser struct {
+	s   string
+	pos int
+}
+
+func (p *addressParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("parsing address %q at offset %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *addressParser) consume(prefix string) bool {
+	if strings.HasPrefix(p.s[p.pos:], prefix) {
+		p.pos += len(prefix)
+		return true
+	}
+	return false
+}
+
+func (p *addressParser) identifier() string {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] != '.' && p.s[p.pos] != '[' {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// key parses an optional instance key: [0] or ["key"].
+func (p *addressParser) key() (interface{}, error) {
+	if !p.consume("[") {
+		return nil, nil
+	}
+	if strings.HasPrefix(p.s[p.pos:], `"`) {
+		end := p.pos + 1
+		for end < len(p.s) && p.s[end] != '"' {
+			if p.s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.s) {
+			return nil, p.errorf("unterminated instance key")
+		}
+		key, err := strconv.Unquote(p.s[p.pos : end+1])
+		if err != nil {
+			return nil, p.errorf("invalid instance key: %v", err)
+		}
+		p.pos = end + 1
+		if !p.consume("]") {
+			return nil, p.errorf("missing ]")
+		}
+		return key, nil
+	}
+	end := strings.IndexByte(p.s[p.pos:], ']')
+	if end < 0 {
+		return nil, p.errorf("missing ]")
+	}
+	key, err := strconv.Atoi(p.s[p.pos : p.pos+end])
+	if err != nil {
+		return nil, p.errorf("invalid instance key: %v", err)
+	}
+	p.pos += end + 1
+	return key, nil
+}
@@ -0,0 +1,87 @@
+package tfplan
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		name       string
+		address    string
+		want       Address
+		wantModule string
+		wantConfig string
+	}{
+		{
+			name:       "Root",
+			address:    "google_compute_network.default",
+			want:       Address{Mode: tfjson.ManagedResourceMode, Type: "google_compute_network", Name: "default"},
+			wantModule: "",
+			wantConfig: "google_compute_network.default",
+		},
+		{
+			name:    "Count",
+			address: "module.gcs_buckets.google_storage_bucket.buckets[0]",
+			want: Address{
+				Module: []ModuleInstance{{Name: "gcs_buckets"}},
+				Mode:   tfjson.ManagedResourceMode,
+				Type:   "google_storage_bucket",
+				Name:   "buckets",
+				Key:    0,
+			},
+			wantModule: "module.gcs_buckets",
+			wantConfig: "google_storage_bucket.buckets",
+		},
+		{
+			name:    "ForEach",
+			address: `module.net["eu.west"].module.subnets[1].google_compute_subnetwork.default["a[0]"]`,
+			want: Address{
+				Module: []ModuleInstance{{Name: "net", Key: "eu.west"}, {Name: "subnets", Key: 1}},
+				Mode:   tfjson.ManagedResourceMode,
+				Type:   "google_compute_subnetwork",
+				Name:   "default",
+				Key:    "a[0]",
+			},
+			wantModule: `module.net["eu.west"].module.subnets[1]`,
+			wantConfig: "google_compute_subnetwork.default",
+		},
+		{
+			name:       "Data",
+			address:    "data.google_project.current",
+			want:       Address{Mode: tfjson.DataResourceMode, Type: "google_project", Name: "current"},
+			wantModule: "",
+			wantConfig: "data.google_project.current",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := ParseAddress(c.address)
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+			require.Equal(t, c.address, got.String())
+			require.Equal(t, c.wantModule, got.ModuleAddress())
+			require.Equal(t, c.wantConfig, got.ConfigAddress())
+		})
+	}
+}
+
+func TestParseAddress_errors(t *testing.T) {
+	for _, address := range []string{
+		"",
+		"google_compute_network",
+		"module.foo",
+		"module.foo.google_compute_network",
+		"google_compute_network.default[",
+		`google_compute_network.default["a]`,
+		"google_compute_network.default[a]",
+		"google_compute_network.default[0]x",
+	} {
+		t.Run(address, func(t *testing.T) {
+			_, err := ParseAddress(address)
+			require.Error(t, err)
+		})
+	}
+}
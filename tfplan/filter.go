@@ -0,0 +1,143 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tfplan
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// Filter selects resource changes by address, resource type or provider.
+//
+// Each pattern is matched against the resource address (e.g.
+// `module.net.*`), the resource type (e.g. `google_compute_firewall`) and
+// the provider name (e.g. `google-beta` or
+// `registry.terraform.io/hashicorp/google-beta`), and matches if any of
+// them matches. Patterns use path.Match syntax, except that `[` and `]`
+// match themselves, so that `module.x["a"].*` matches the resources of a
+// module instance. Escaping them as `\[` and `\]` is still allowed.
+type Filter struct {
+	// Include keeps only the resource changes matching at least one
+	// pattern. An empty Include keeps all resource changes.
+	Include []string
+	// Exclude drops the resource changes matching any pattern, even if
+	// they are included.
+	Exclude []string
+}
+
+// FilteredResource is a resource change dropped by a Filter.
+type FilteredResource struct {
+	Address  string `json:"address"`
+	Type     string `json:"type"`
+	Provider string `json:"provider"`
+	Reason   string `json:"reason"`
+}
+
+// Validate checks that all patterns of the filter are well-formed.
+func (f Filter) Validate() error {
+	for _, p := range append(append([]string{}, f.Include...), f.Exclude...) {
+		if _, err := path.Match(literalBrackets(p), ""); err != nil {
+			return fmt.Errorf("invalid filter pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Apply splits the given resource changes into the ones kept by the filter
+// and the ones filtered out, in their original order.
+func (f Filter) Apply(changes []*tfjson.ResourceChange) ([]*tfjson.ResourceChange, []FilteredResource) {
+	var kept []*tfjson.ResourceChange
+	var filtered []FilteredResource
+	for _, rc := range changes {
+		reason := f.reason(rc)
+		if reason == "" {
+			kept = append(kept, rc)
+			continue
+		}
+		filtered = append(filtered, FilteredResource{
+			Address:  rc.Address,
+			Type:     rc.Type,
+			Provider: rc.ProviderName,
+			Reason:   reason,
+		})
+	}
+	return kept, filtered
+}
+
+// reason returns why the resource change is filtered out, or "" if it is kept.
+func (f Filter) reason(rc *tfjson.ResourceChange) string {
+	if len(f.Include) > 0 {
+		if _, ok := firstMatch(f.Include, rc); !ok {
+			return "not matched by any include pattern"
+		}
+	}
+	if p, ok := firstMatch(f.Exclude, rc); ok {
+		return fmt.Sprintf("excluded by pattern %q", p)
+	}
+	return ""
+}
+
+func firstMatch(patterns []string, rc *tfjson.ResourceChange) (string, bool) {
+	for _, p := range patterns {
+		if matchResourceChange(p, rc) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+func matchResourceChange(pattern string, rc *tfjson.ResourceChange) bool {
+	candidates := []string{rc.Address, rc.Type, rc.ProviderName}
+	// Provider names are fully qualified in Terraform 0.13+ plans
+	// (registry.terraform.io/hashicorp/google); also match the short name.
+	if i := strings.LastIndex(rc.ProviderName, "/"); i >= 0 {
+		candidates = append(candidates, rc.ProviderName[i+1:])
+	}
+	pattern = literalBrackets(pattern)
+	for _, c := range candidates {
+		if c == "" {
+			continue
+		}
+		if ok, _ := path.Match(pattern, c); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// literalBrackets escapes the unescaped `[` and `]` of a path.Match pattern,
+// which would otherwise start a character class: in resource addresses they
+// enclose count indexes and for_each keys.
+func literalBrackets(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '\\':
+			b.WriteByte(c)
+			if i+1 < len(pattern) {
+				i++
+				b.WriteByte(pattern[i])
+			}
+		case '[', ']':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
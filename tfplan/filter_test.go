@@ -0,0 +1,113 @@
+package tfplan
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	changes := []*tfjson.ResourceChange{
+		{Address: "google_project.p", Type: "google_project", ProviderName: "registry.terraform.io/hashicorp/google"},
+		{Address: "module.net.google_compute_network.n", Type: "google_compute_network", ProviderName: "registry.terraform.io/hashicorp/google"},
+		{Address: "module.net.module.sub.google_compute_subnetwork.s[0]", Type: "google_compute_subnetwork", ProviderName: "registry.terraform.io/hashicorp/google-beta"},
+		{Address: "module.network.random_id.r", Type: "random_id", ProviderName: "registry.terraform.io/hashicorp/random"},
+	}
+	cases := []struct {
+		name         string
+		filter       Filter
+		wantKept     []string
+		wantFiltered []FilteredResource
+	}{
+		{
+			name:     "Empty",
+			wantKept: []string{changes[0].Address, changes[1].Address, changes[2].Address, changes[3].Address},
+		},
+		{
+			name:     "IncludeModule",
+			filter:   Filter{Include: []string{"module.net.*"}},
+			wantKept: []string{changes[1].Address, changes[2].Address},
+			wantFiltered: []FilteredResource{
+				{Address: changes[0].Address, Type: "google_project", Provider: changes[0].ProviderName, Reason: "not matched by any include pattern"},
+				{Address: changes[3].Address, Type: "random_id", Provider: changes[3].ProviderName, Reason: "not matched by any include pattern"},
+			},
+		},
+		{
+			name:     "ExcludeTypeAndProvider",
+			filter:   Filter{Exclude: []string{"google_project", "random"}},
+			wantKept: []string{changes[1].Address, changes[2].Address},
+			wantFiltered: []FilteredResource{
+				{Address: changes[0].Address, Type: "google_project", Provider: changes[0].ProviderName, Reason: `excluded by pattern "google_project"`},
+				{Address: changes[3].Address, Type: "random_id", Provider: changes[3].ProviderName, Reason: `excluded by pattern "random"`},
+			},
+		},
+		{
+			name:     "IncludeAndExclude",
+			filter:   Filter{Include: []string{"google_compute_*"}, Exclude: []string{"google-beta", "google_project"}},
+			wantKept: []string{changes[1].Address},
+			wantFiltered: []FilteredResource{
+				{Address: changes[0].Address, Type: "google_project", Provider: changes[0].ProviderName, Reason: "not matched by any include pattern"},
+				{Address: changes[2].Address, Type: "google_compute_subnetwork", Provider: changes[2].ProviderName, Reason: `excluded by pattern "google-beta"`},
+				{Address: changes[3].Address, Type: "random_id", Provider: changes[3].ProviderName, Reason: "not matched by any include pattern"},
+			},
+		},
+		{
+			name:     "EscapedIndex",
+			filter:   Filter{Include: []string{`module.net.module.sub.*\[0\]`}},
+			wantKept: []string{changes[2].Address},
+			wantFiltered: []FilteredResource{
+				{Address: changes[0].Address, Type: "google_project", Provider: changes[0].ProviderName, Reason: "not matched by any include pattern"},
+				{Address: changes[1].Address, Type: "google_compute_network", Provider: changes[1].ProviderName, Reason: "not matched by any include pattern"},
+				{Address: changes[3].Address, Type: "random_id", Provider: changes[3].ProviderName, Reason: "not matched by any include pattern"},
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.NoError(t, c.filter.Validate())
+			kept, filtered := c.filter.Apply(changes)
+			var keptAddresses []string
+			for _, rc := range kept {
+				keptAddresses = append(keptAddresses, rc.Address)
+			}
+			require.Equal(t, c.wantKept, keptAddresses)
+			require.Equal(t, c.wantFiltered, filtered)
+		})
+	}
+}
+
+func TestFilter_instanceKeys(t *testing.T) {
+	changes := []*tfjson.ResourceChange{
+		{Address: `module.x["a"].google_project.p`, Type: "google_project"},
+		{Address: `module.x["b"].google_project.p`, Type: "google_project"},
+		{Address: `module.x["a"].google_compute_network.n[0]`, Type: "google_compute_network"},
+	}
+	cases := []struct {
+		name     string
+		pattern  string
+		wantKept []string
+	}{
+		{"Key", `module.x["a"].*`, []string{changes[0].Address, changes[2].Address}},
+		{"EscapedKey", `module.x\["b"\].*`, []string{changes[1].Address}},
+		{"Index", `*.n[0]`, []string{changes[2].Address}},
+		{"NotAClass", `module.x[ab].*`, nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f := Filter{Include: []string{c.pattern}}
+			require.NoError(t, f.Validate())
+			kept, _ := f.Apply(changes)
+			var keptAddresses []string
+			for _, rc := range kept {
+				keptAddresses = append(keptAddresses, rc.Address)
+			}
+			require.Equal(t, c.wantKept, keptAddresses)
+		})
+	}
+}
+
+func TestFilter_Validate(t *testing.T) {
+	require.NoError(t, Filter{Exclude: []string{"module.net[0"}}.Validate())
+	require.Error(t, Filter{Exclude: []string{`module.net\`}}.Validate())
+}
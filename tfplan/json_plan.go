@@ -14,11 +14,42 @@
 package tfplan
 
 import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"time"
+
 	"github.com/hashicorp/terraform-json"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/pkg/errors"
 )
 
+// checkFormatVersion returns an error if a plan, state or provider schemas
+// format version is missing or not supported. The terraform-json version in
+// use only accepts the 0.x formats: the 1.x formats of Terraform 1.1+ are
+// backwards compatible with them and carry new fields (e.g.
+// previous_address) that this package reads separately.
+func checkFormatVersion(kind, version string) error {
+	if version == "" {
+		return errors.Errorf("unexpected %s input, format version is missing", kind)
+	}
+	if major := strings.SplitN(version, ".", 2)[0]; major != "0" && major != "1" {
+		return errors.Errorf("unsupported %s format version: expected 0.x or 1.x, got %q", kind, version)
+	}
+	return nil
+}
+
+// rawPlan and rawState decode a plan and its prior state without the format
+// version checks of terraform-json (see checkFormatVersion).
+type rawPlan tfjson.Plan
+type rawState tfjson.State
+
+type planJSON struct {
+	*rawPlan
+	// PriorState shadows the prior state of rawPlan.
+	PriorState *rawState `json:"prior_state,omitempty"`
+}
+
 func IsCreate(rc *tfjson.ResourceChange) bool {
 	return len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == "create"
 }
@@ -31,10 +62,20 @@ func IsDeleteCreate(rc *tfjson.ResourceChange) bool {
 	return len(rc.Change.Actions) == 2 && rc.Change.Actions[0] == "delete"
 }
 
+// IsCreateDelete reports whether the resource change replaces the resource by
+// creating the replacement first (create_before_destroy).
+func IsCreateDelete(rc *tfjson.ResourceChange) bool {
+	return len(rc.Change.Actions) == 2 && rc.Change.Actions[0] == "create" && rc.Change.Actions[1] == "delete"
+}
+
 func IsDelete(rc *tfjson.ResourceChange) bool {
 	return len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == "delete"
 }
 
+func IsNoOp(rc *tfjson.ResourceChange) bool {
+	return len(rc.Change.Actions) == 1 && rc.Change.Actions[0] == "no-op"
+}
+
 // compatibility shim until ResourceChange is expected by all callers.
 func Kind(rc *tfjson.ResourceChange) string {
 	return rc.Type
@@ -57,18 +98,119 @@ func ComposeTF12Resources(data []byte, _ map[string]*schema.Resource) ([]tfjson.
 	return rcs, nil
 }
 
-// ReadResourceChanges returns the list of resource changes from a json plan
-func ReadResourceChanges(data []byte) ([]*tfjson.ResourceChange, error) {
-	plan := tfjson.Plan{}
-	err := plan.UnmarshalJSON(data)
+// Plan is a terraform JSON plan. It wraps the terraform-json representation
+// and adds the sections that the vendored terraform-json version does not
+// model yet. See ActionOf, ParseAddress and the module lookups in modules.go
+// for working with the resource changes of a plan.
+type Plan struct {
+	tfjson.Plan
+
+	// ResourceDrift lists the changes Terraform detected outside of Terraform
+	// while refreshing state (Terraform 0.15.4+). The "after" value of each
+	// entry is the refreshed object.
+	ResourceDrift []*tfjson.ResourceChange
+
+	// PreviousAddresses maps the address of each resource that is moved by
+	// this plan (Terraform 1.1+ `moved` blocks) to its previous address.
+	PreviousAddresses map[string]string
+
+	// Timestamp is the time the plan was created at (Terraform 1.5+), or the
+	// zero time if the plan does not record it.
+	Timestamp time.Time
+}
+
+// PreviousAddress returns the address a resource change was moved from, or
+// "" if the resource was not moved.
+func (p *Plan) PreviousAddress(rc *tfjson.ResourceChange) string {
+	return p.PreviousAddresses[rc.Address]
+}
+
+// IsMove reports whether a resource change moves the resource to a new
+// address. A move may come with no other action or with an update.
+func (p *Plan) IsMove(rc *tfjson.ResourceChange) bool {
+	return p.PreviousAddress(rc) != ""
+}
+
+// planExtensions holds the plan sections and fields that are not part of
+// tfjson.Plan, and the values of resource changes decoded with json.Number.
+type planExtensions struct {
+	Timestamp       string                   `json:"timestamp,omitempty"`
+	ResourceDrift   []*tfjson.ResourceChange `json:"resource_drift,omitempty"`
+	ResourceChanges []struct {
+		Address         string `json:"address,omitempty"`
+		PreviousAddress string `json:"previous_address,omitempty"`
+		Change          struct {
+			Before interface{} `json:"before,omitempty"`
+			After  interface{} `json:"after,omitempty"`
+		} `json:"change"`
+	} `json:"resource_changes,omitempty"`
+}
+
+// ReadPlan parses and validates a json plan.
+func ReadPlan(data []byte) (*Plan, error) {
+	plan := &Plan{}
+	raw := planJSON{rawPlan: (*rawPlan)(&plan.Plan)}
+	err := json.Unmarshal(data, &raw)
 	if err != nil {
 		return nil, errors.Wrap(err, "reading JSON plan")
 	}
+	plan.Plan.PriorState = (*tfjson.State)(raw.PriorState)
 
-	err = plan.Validate()
+	err = checkFormatVersion("plan", plan.FormatVersion)
 	if err != nil {
 		return nil, errors.Wrap(err, "validating JSON plan")
 	}
+	if plan.PriorState != nil {
+		if err := checkFormatVersion("state", plan.PriorState.FormatVersion); err != nil {
+			return nil, errors.Wrap(err, "validating JSON plan")
+		}
+	}
+
+	// Decode numbers as json.Number so that large integers (e.g. project
+	// numbers) keep their precision; tfjson decodes them as float64.
+	ext := planExtensions{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&ext); err != nil {
+		return nil, errors.Wrap(err, "reading JSON plan extensions")
+	}
+	if ext.Timestamp != "" {
+		plan.Timestamp, err = time.Parse(time.RFC3339, ext.Timestamp)
+		if err != nil {
+			return nil, errors.Wrap(err, "reading JSON plan timestamp")
+		}
+	}
+	plan.ResourceDrift = ext.ResourceDrift
+	plan.PreviousAddresses = make(map[string]string)
+	for i, rc := range ext.ResourceChanges {
+		if rc.PreviousAddress != "" && rc.PreviousAddress != rc.Address {
+			plan.PreviousAddresses[rc.Address] = rc.PreviousAddress
+		}
+		if i < len(plan.ResourceChanges) && plan.ResourceChanges[i].Change != nil {
+			plan.ResourceChanges[i].Change.Before = rc.Change.Before
+			plan.ResourceChanges[i].Change.After = rc.Change.After
+		}
+	}
+
+	return plan, nil
+}
 
+// ReadResourceChanges returns the list of resource changes from a json plan
+func ReadResourceChanges(data []byte) ([]*tfjson.ResourceChange, error) {
+	plan, err := ReadPlan(data)
+	if err != nil {
+		return nil, err
+	}
 	return plan.ResourceChanges, nil
 }
+
+// ReadResourceDrift returns the list of resource drift entries from a json
+// plan. Plans created by Terraform versions that do not report drift return
+// an empty list.
+func ReadResourceDrift(data []byte) ([]*tfjson.ResourceChange, error) {
+	plan, err := ReadPlan(data)
+	if err != nil {
+		return nil, err
+	}
+	return plan.ResourceDrift, nil
+}
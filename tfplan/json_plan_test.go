@@ -3,7 +3,9 @@ package tfplan
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/stretchr/testify/require"
 )
 
@@ -169,3 +171,255 @@ func TestReadResourceChanges(t *testing.T) {
 	}
 	require.JSONEq(t, string(wantJSON), string(gotJSON))
 }
+
+func TestReadResourceDrift(t *testing.T) {
+	data := []byte(`
+{
+	"format_version": "0.2",
+	"terraform_version": "1.0.1",
+	"resource_drift": [
+		{
+			"address": "google_compute_firewall.default",
+			"mode": "managed",
+			"type": "google_compute_firewall",
+			"name": "default",
+			"provider_name": "registry.terraform.io/hashicorp/google",
+			"change": {
+				"actions": ["update"],
+				"before": {"source_ranges": []},
+				"after": {"source_ranges": ["0.0.0.0/0"]}
+			}
+		}
+	],
+	"resource_changes": []
+}
+`)
+	wantJSON := []byte(`
+[
+	{
+		"address": "google_compute_firewall.default",
+		"mode": "managed",
+		"type": "google_compute_firewall",
+		"name": "default",
+		"provider_name": "registry.terraform.io/hashicorp/google",
+		"change": {
+			"actions": ["update"],
+			"before": {"source_ranges": []},
+			"after": {"source_ranges": ["0.0.0.0/0"]}
+		}
+	}
+]
+`)
+	drift, err := ReadResourceDrift(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", string(data), err)
+	}
+	gotJSON, err := json.Marshal(drift)
+	if err != nil {
+		t.Fatalf("marshaling: %v", err)
+	}
+	require.JSONEq(t, string(wantJSON), string(gotJSON))
+}
+
+func TestReadResourceDrift_noDrift(t *testing.T) {
+	drift, err := ReadResourceDrift(newPlan(t))
+	if err != nil {
+		t.Fatalf("parsing plan: %v", err)
+	}
+	require.Empty(t, drift)
+}
+
+func TestReadPlan_moves(t *testing.T) {
+	data := []byte(`
+{
+	"format_version": "1.0",
+	"terraform_version": "1.1.0",
+	"prior_state": {
+		"format_version": "1.0",
+		"terraform_version": "1.1.0",
+		"values": {"root_module": {}}
+	},
+	"resource_changes": [
+		{
+			"address": "module.net.google_compute_network.default",
+			"previous_address": "google_compute_network.default",
+			"mode": "managed",
+			"type": "google_compute_network",
+			"name": "default",
+			"provider_name": "registry.terraform.io/hashicorp/google",
+			"change": {
+				"actions": ["no-op"],
+				"before": {"name": "default"},
+				"after": {"name": "default"}
+			}
+		},
+		{
+			"address": "google_compute_firewall.default",
+			"mode": "managed",
+			"type": "google_compute_firewall",
+			"name": "default",
+			"provider_name": "registry.terraform.io/hashicorp/google",
+			"change": {
+				"actions": ["update"],
+				"before": {"name": "default"},
+				"after": {"name": "default"}
+			}
+		}
+	]
+}
+`)
+	plan, err := ReadPlan(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", string(data), err)
+	}
+	require.Equal(t, map[string]string{
+		"module.net.google_compute_network.default": "google_compute_network.default",
+	}, plan.PreviousAddresses)
+
+	moved, updated := plan.ResourceChanges[0], plan.ResourceChanges[1]
+	require.True(t, plan.IsMove(moved))
+	require.True(t, IsNoOp(moved))
+	require.Equal(t, "google_compute_network.default", plan.PreviousAddress(moved))
+	require.False(t, plan.IsMove(updated))
+	require.Equal(t, "", plan.PreviousAddress(updated))
+}
+
+func TestActionOf(t *testing.T) {
+	cases := []struct {
+		actions tfjson.Actions
+		want    Action
+	}{
+		{tfjson.Actions{"no-op"}, ActionNoOp},
+		{tfjson.Actions{"create"}, ActionCreate},
+		{tfjson.Actions{"read"}, ActionRead},
+		{tfjson.Actions{"update"}, ActionUpdate},
+		{tfjson.Actions{"delete"}, ActionDelete},
+		{tfjson.Actions{"delete", "create"}, ActionDeleteCreate},
+		{tfjson.Actions{"create", "delete"}, ActionCreateDelete},
+		{tfjson.Actions{"change"}, ActionUnknown},
+	}
+	for _, c := range cases {
+		t.Run(c.want.String(), func(t *testing.T) {
+			rc := &tfjson.ResourceChange{Change: &tfjson.Change{Actions: c.actions}}
+			require.Equal(t, c.want, ActionOf(rc))
+		})
+	}
+}
+
+func TestReadPlan_numbers(t *testing.T) {
+	data := []byte(`
+{
+	"format_version": "0.2",
+	"terraform_version": "1.0.1",
+	"resource_changes": [
+		{
+			"address": "google_project.default",
+			"mode": "managed",
+			"type": "google_project",
+			"name": "default",
+			"provider_name": "registry.terraform.io/hashicorp/google",
+			"change": {
+				"actions": ["update"],
+				"before": {"number": 9007199254740993},
+				"after": {"number": 9007199254740993, "disk_size_gb": 1.5}
+			}
+		}
+	]
+}
+`)
+	plan, err := ReadPlan(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", string(data), err)
+	}
+	change := plan.ResourceChanges[0].Change
+	require.Equal(t, map[string]interface{}{"number": json.Number("9007199254740993")}, change.Before)
+	require.Equal(t, map[string]interface{}{
+		"number":       json.Number("9007199254740993"),
+		"disk_size_gb": json.Number("1.5"),
+	}, change.After)
+}
+
+func TestReadPlan_timestamp(t *testing.T) {
+	data := []byte(`
+{
+	"format_version": "1.2",
+	"terraform_version": "1.5.0",
+	"timestamp": "2023-06-12T09:30:00Z",
+	"resource_changes": []
+}
+`)
+	plan, err := ReadPlan(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", string(data), err)
+	}
+	require.Equal(t, time.Date(2023, time.June, 12, 9, 30, 0, 0, time.UTC), plan.Timestamp.UTC())
+
+	plan, err = ReadPlan(newPlan(t))
+	if err != nil {
+		t.Fatalf("parsing plan: %v", err)
+	}
+	require.True(t, plan.Timestamp.IsZero())
+}
+
+func TestReadProviderSchemas(t *testing.T) {
+	data := []byte(`
+{
+	"format_version": "1.0",
+	"provider_schemas": {
+		"registry.terraform.io/hashicorp/google": {
+			"resource_schemas": {
+				"google_compute_network": {
+					"version": 0,
+					"block": {
+						"attributes": {
+							"name": {"type": "string", "required": true}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+`)
+	schemas, err := ReadProviderSchemas(data)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", string(data), err)
+	}
+	network := schemas.Schemas["registry.terraform.io/hashicorp/google"].ResourceSchemas["google_compute_network"]
+	require.True(t, network.Block.Attributes["name"].Required)
+
+	_, err = ReadProviderSchemas([]byte(`{"format_version": "2.0"}`))
+	require.Error(t, err)
+}
+
+func TestReadPlan_formatVersion(t *testing.T) {
+	planFormats := append([]string(nil), tfjson.PlanFormatVersions...)
+	stateFormats := append([]string(nil), tfjson.StateFormatVersions...)
+
+	plan, err := ReadPlan([]byte(`
+{
+	"format_version": "1.1",
+	"prior_state": {
+		"format_version": "1.0",
+		"values": {"root_module": {}}
+	},
+	"resource_changes": []
+}
+`))
+	require.NoError(t, err)
+	require.NotNil(t, plan.PriorState)
+	require.Equal(t, "1.0", plan.PriorState.FormatVersion)
+
+	for _, data := range []string{
+		`{"resource_changes": []}`,
+		`{"format_version": "2.0"}`,
+		`{"format_version": "1.0", "prior_state": {"format_version": "2.0"}}`,
+	} {
+		_, err := ReadPlan([]byte(data))
+		require.Error(t, err, data)
+	}
+
+	// The supported formats are checked without changing terraform-json.
+	require.Equal(t, planFormats, tfjson.PlanFormatVersions)
+	require.Equal(t, stateFormats, tfjson.StateFormatVersions)
+}
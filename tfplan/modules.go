@@ -0,0 +1,157 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tfplan
+
+import (
+	"sort"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+// errStopWalk stops a module walk early without reporting an error.
+var errStopWalk = errors.New("stop walk")
+
+// WalkStateModules calls fn for module and all of its descendants, parents
+// before children. It stops at the first error returned by fn.
+func WalkStateModules(module *tfjson.StateModule, fn func(*tfjson.StateModule) error) error {
+	if module == nil {
+		return nil
+	}
+	if err := fn(module); err != nil {
+		return err
+	}
+	for _, child := range module.ChildModules {
+		if err := WalkStateModules(child, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WalkConfigModules calls fn for a configuration module and all of the
+// modules it calls, parents before children. path holds the names of the
+// module calls leading to each module. Module calls are visited in name
+// order. It stops at the first error returned
+// by fn.
+func WalkConfigModules(module *tfjson.ConfigModule, fn func(path []string, module *tfjson.ConfigModule) error) error {
+	return walkConfigModules(nil, module, fn)
+}
+
+func walkConfigModules(path []string, module *tfjson.ConfigModule, fn func([]string, *tfjson.ConfigModule) error) error {
+	if module == nil {
+		return nil
+	}
+	if err := fn(path, module); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(module.ModuleCalls))
+	for name := range module.ModuleCalls {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		childPath := append(append([]string{}, path...), name)
+		if err := walkConfigModules(childPath, module.ModuleCalls[name].Module, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PlannedResource returns the planned values of the resource instance with
+// the given address, or nil if the plan has none.
+func (p *Plan) PlannedResource(address string) *tfjson.StateResource {
+	if p.PlannedValues == nil {
+		return nil
+	}
+	return findStateResource(p.PlannedValues.RootModule, address)
+}
+
+// PriorResource returns the prior state of the resource instance with the
+// given address, or nil if it did not exist before the plan.
+func (p *Plan) PriorResource(address string) *tfjson.StateResource {
+	if p.PriorState == nil || p.PriorState.Values == nil {
+		return nil
+	}
+	return findStateResource(p.PriorState.Values.RootModule, address)
+}
+
+// ConfigResource returns the configuration block of the resource a change
+// belongs to, or nil if the plan does not include it.
+func (p *Plan) ConfigResource(rc *tfjson.ResourceChange) (*tfjson.ConfigResource, error) {
+	addr, err := ParseAddress(rc.Address)
+	if err != nil {
+		return nil, err
+	}
+	if p.Config == nil {
+		return nil, nil
+	}
+	module := p.Config.RootModule
+	for _, name := range addr.ModulePath() {
+		if module == nil {
+			return nil, nil
+		}
+		call, ok := module.ModuleCalls[name]
+		if !ok {
+			return nil, nil
+		}
+		module = call.Module
+	}
+	if module == nil {
+		return nil, nil
+	}
+	configAddress := addr.ConfigAddress()
+	for _, r := range module.Resources {
+		if r.Address == configAddress {
+			return r, nil
+		}
+	}
+	return nil, nil
+}
+
+// ProviderConfigValue returns the value of an argument of the default (not
+// aliased) configuration of a provider in the root module, e.g. the region of
+// "google". It returns "" if the argument is not set to a constant string.
+func (p *Plan) ProviderConfigValue(provider, argument string) string {
+	if p.Config == nil {
+		return ""
+	}
+	for _, pc := range p.Config.ProviderConfigs {
+		if pc.Name != provider || pc.Alias != "" || pc.ModuleAddress != "" {
+			continue
+		}
+		expr, ok := pc.Expressions[argument]
+		if !ok || expr == nil || expr.ExpressionData == nil {
+			return ""
+		}
+		s, _ := expr.ConstantValue.(string)
+		return s
+	}
+	return ""
+}
+
+func findStateResource(root *tfjson.StateModule, address string) *tfjson.StateResource {
+	var found *tfjson.StateResource
+	_ = WalkStateModules(root, func(m *tfjson.StateModule) error {
+		for _, r := range m.Resources {
+			if r.Address == address && r.DeposedKey == "" {
+				found = r
+				return errStopWalk
+			}
+		}
+		return nil
+	})
+	return found
+}
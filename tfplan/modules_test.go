@@ -0,0 +1,120 @@
+package tfplan
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+func newPlanWithConfiguration(t *testing.T) *Plan {
+	t.Helper()
+	plan, err := ReadPlan([]byte(`
+{
+	"format_version": "0.2",
+	"planned_values": {
+		"root_module": {
+			"resources": [
+				{"address": "google_compute_network.default", "values": {"name": "planned"}}
+			],
+			"child_modules": [
+				{
+					"address": "module.net[\"eu\"]",
+					"resources": [
+						{"address": "module.net[\"eu\"].google_compute_subnetwork.default[0]", "values": {"name": "planned-eu"}}
+					]
+				}
+			]
+		}
+	},
+	"prior_state": {
+		"format_version": "0.2",
+		"values": {
+			"root_module": {
+				"resources": [
+					{"address": "google_compute_network.default", "values": {"name": "prior"}}
+				]
+			}
+		}
+	},
+	"configuration": {
+		"provider_config": {
+			"google": {
+				"name": "google",
+				"expressions": {
+					"region": {"constant_value": "europe-west1"},
+					"project": {"references": ["var.project"]}
+				}
+			},
+			"google.us": {
+				"name": "google",
+				"alias": "us",
+				"expressions": {"region": {"constant_value": "us-east1"}}
+			}
+		},
+		"root_module": {
+			"resources": [
+				{"address": "google_compute_network.default", "mode": "managed", "type": "google_compute_network", "name": "default"}
+			],
+			"module_calls": {
+				"net": {
+					"source": "./net",
+					"module": {
+						"resources": [
+							{"address": "google_compute_subnetwork.default", "mode": "managed", "type": "google_compute_subnetwork", "name": "default"}
+						]
+					}
+				}
+			}
+		}
+	},
+	"resource_changes": []
+}
+`))
+	require.NoError(t, err)
+	return plan
+}
+
+func TestWalkStateModules(t *testing.T) {
+	plan := newPlanWithConfiguration(t)
+	var got []string
+	err := WalkStateModules(plan.PlannedValues.RootModule, func(m *tfjson.StateModule) error {
+		got = append(got, m.Address)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"", `module.net["eu"]`}, got)
+}
+
+func TestWalkConfigModules(t *testing.T) {
+	plan := newPlanWithConfiguration(t)
+	var got [][]string
+	err := WalkConfigModules(plan.Config.RootModule, func(path []string, m *tfjson.ConfigModule) error {
+		got = append(got, path)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, [][]string{nil, {"net"}}, got)
+}
+
+func TestPlanResourceLookups(t *testing.T) {
+	plan := newPlanWithConfiguration(t)
+
+	require.Equal(t, "planned", plan.PlannedResource("google_compute_network.default").AttributeValues["name"])
+	require.Equal(t, "planned-eu", plan.PlannedResource(`module.net["eu"].google_compute_subnetwork.default[0]`).AttributeValues["name"])
+	require.Equal(t, "prior", plan.PriorResource("google_compute_network.default").AttributeValues["name"])
+	require.Nil(t, plan.PriorResource(`module.net["eu"].google_compute_subnetwork.default[0]`))
+
+	cfg, err := plan.ConfigResource(&tfjson.ResourceChange{Address: `module.net["eu"].google_compute_subnetwork.default[0]`})
+	require.NoError(t, err)
+	require.Equal(t, "google_compute_subnetwork", cfg.Type)
+
+	cfg, err = plan.ConfigResource(&tfjson.ResourceChange{Address: "module.other.google_compute_subnetwork.default"})
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+
+	require.Equal(t, "europe-west1", plan.ProviderConfigValue("google", "region"))
+	require.Empty(t, plan.ProviderConfigValue("google", "project"))
+	require.Empty(t, plan.ProviderConfigValue("google", "zone"))
+	require.Empty(t, plan.ProviderConfigValue("google-beta", "region"))
+}
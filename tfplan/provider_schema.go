@@ -0,0 +1,37 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package tfplan
+
+import (
+	"encoding/json"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/pkg/errors"
+)
+
+// ReadProviderSchemas parses and validates the output of
+// `terraform providers schema -json`.
+func ReadProviderSchemas(data []byte) (*tfjson.ProviderSchemas, error) {
+	// Decoded without the format version checks of terraform-json, see
+	// checkFormatVersion.
+	type rawProviderSchemas tfjson.ProviderSchemas
+	schemas := &rawProviderSchemas{}
+	if err := json.Unmarshal(data, schemas); err != nil {
+		return nil, errors.Wrap(err, "reading JSON provider schemas")
+	}
+	if err := checkFormatVersion("provider schemas", schemas.FormatVersion); err != nil {
+		return nil, errors.Wrap(err, "validating JSON provider schemas")
+	}
+	return (*tfjson.ProviderSchemas)(schemas), nil
+}